@@ -4,6 +4,7 @@ import (
 	"go_task_api/models"
 	"net/http"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // @Summary Admin-only: List all users
@@ -52,6 +53,30 @@ func AdminUpdateUserRole(c *gin.Context) {
 
 	user.Role = input.Role
 	DB.Save(&user)
+	// Access tokens carry the role, so make the user's clients fetch new ones
+	DB.Model(&user).Update("token_version", gorm.Expr("token_version + 1"))
 
 	c.JSON(http.StatusOK, gin.H{"message": "User role updated", "user": user.Username, "new_role": user.Role})
 }
+
+// @Summary Admin-only: Revoke all sessions of a user
+// @Description Logs the user out on every device; they have to log in again.
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/revoke-sessions [post]
+func AdminRevokeSessions(c *gin.Context) {
+	var user models.User
+	if err := DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := revokeAllSessions(DB, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
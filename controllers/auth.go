@@ -2,9 +2,12 @@
 package controllers
 
 import (
+	"errors"
 	"go_task_api/models"
-	"go_task_api/utils"
+	"log"
 	"net/http"
+	"net/mail"
+	"os"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,17 +15,44 @@ import (
 
 var DB *gorm.DB
 
+// Registration modes, set with REGISTRATION_MODE
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite-only"
+	RegistrationClosed     = "closed"
+)
+
+// RegistrationMode controls who may sign up through POST /register
+var RegistrationMode = RegistrationOpen
+
+var (
+	errUsernameTaken = errors.New("Username already exists")
+	errInvalidEmail  = errors.New("Invalid email address")
+)
+
 func InitAuth(db *gorm.DB) {
 	DB = db
+
+	switch mode := os.Getenv("REGISTRATION_MODE"); mode {
+	case "", RegistrationOpen:
+		RegistrationMode = RegistrationOpen
+	case RegistrationInviteOnly, RegistrationClosed:
+		RegistrationMode = mode
+	default:
+		log.Printf("unknown REGISTRATION_MODE %q, closing registration", mode)
+		RegistrationMode = RegistrationClosed
+	}
 }
 
-// @Summary Register a new user (optionally as admin)
+// @Summary Register a new user
+// @Description Accounts are created as regular users, except the very first one, which becomes an admin, and ones accepting an invitation, which get the invited role and join the invited projects. Depending on the registration mode sign-up needs an invitation or is closed.
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param input body models.RegisterRequest true "User credentials with optional role (admin or user)"
+// @Param input body models.RegisterRequest true "User credentials"
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /register [post]
 func Register(c *gin.Context) {
 	// var req struct {
@@ -36,36 +66,109 @@ func Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
-	
-	var existing models.User
-	if err := DB.Where("username = ?", req.Username).First(&existing).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
+
+	switch {
+	case RegistrationMode == RegistrationClosed:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is closed"})
+		return
+	case RegistrationMode == RegistrationInviteOnly && req.InviteToken == "":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is by invitation only"})
 		return
 	}
 
-	// default to "user" if role is not provided
-	role := req.Role
-	if role != "admin" {
-		role = "user"
+	var user models.User
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// Public sign-up never grants a role, whatever the request asks for;
+		// only an invitation can
+		role, email := "user", req.Email
+		var invitation models.Invitation
+		if req.InviteToken != "" {
+			var err error
+			if invitation, err = claimInvitation(tx, req.InviteToken); err != nil {
+				return err
+			}
+			role = invitation.Role
+			if invitation.Email != "" {
+				email = invitation.Email
+			}
+		}
+
+		var err error
+		user, err = createUser(tx, req.Username, req.Password, email, role)
+		if err != nil || invitation.ID == 0 {
+			return err
+		}
+		if err := tx.Model(&invitation).Update("accepted_by", user.ID).Error; err != nil {
+			return err
+		}
+		for _, projectID := range invitation.ProjectIDs {
+			if err := addProjectMember(tx, projectID, user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errUsernameTaken) || errors.Is(err, errInvalidEmail) || errors.Is(err, errInvalidInvitation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
 	}
 
-	user := models.User{
-		Username: req.Username,
-		Role:     role,
+	if user.Email != "" {
+		if err := sendEmailVerification(DB, user); err != nil {
+			log.Printf("email verification for user %d: %v", user.ID, err)
+		}
 	}
+	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+}
 
-	// user := models.User{Username: req.Username}
-	if err := user.SetPassword(req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error setting password"})
-		return
+// CreateAdmin creates an admin account, for bootstrapping from the command line
+func CreateAdmin(db *gorm.DB, username, password, email string) error {
+	if username == "" || password == "" {
+		return errors.New("username and password are required")
 	}
+	_, err := createUser(db, username, password, email, "admin")
+	return err
+}
 
-	if err := DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-		return
+// createUser checks and creates an account with the given role. The first
+// account ever created is an admin regardless, so a fresh install can be set up.
+func createUser(db *gorm.DB, username, password, email, role string) (models.User, error) {
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil {
+			return models.User{}, errInvalidEmail
+		}
+		email = addr.Address
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	user := models.User{
+		Username: username,
+		Email:    email,
+		Role:     role,
+	}
+	if err := user.SetPassword(password); err != nil {
+		return models.User{}, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing models.User
+		if err := tx.Where("username = ?", username).First(&existing).Error; err == nil {
+			return errUsernameTaken
+		}
+		var count int64
+		if err := tx.Model(&models.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			user.Role = "admin"
+		}
+		return tx.Create(&user).Error
+	})
+	return user, err
 }
 
 // @Summary Login with username and password
@@ -73,7 +176,7 @@ func Register(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param input body models.LoginRequest true "User credentials"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} models.TokenResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Router /login [post]
@@ -102,11 +205,19 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID)
+	var tokens models.TokenResponse
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		session, err := startSession(tx, user.ID, c)
+		if err != nil {
+			return err
+		}
+		tokens, err = issueTokens(tx, user.ID, session.FamilyID)
+		return err
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
 }
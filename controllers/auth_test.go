@@ -16,7 +16,7 @@ import (
 
 func setupTestDB() *gorm.DB {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&models.User{}, &models.Task{})
+	db.AutoMigrate(models.All()...)
 	InitAuth(db)
 	return db
 }
@@ -71,3 +71,35 @@ func TestRegisterAndLogin(t *testing.T) {
 		t.Fatalf("Token not returned")
 	}
 }
+
+func TestRegistrationLockdown(t *testing.T) {
+	db := setupTestDB()
+	r := setupRouter()
+	t.Cleanup(func() { RegistrationMode = RegistrationOpen })
+
+	doJSON(r, "POST", "/register", "", `{"username": "first", "password": "secret"}`)
+	doJSON(r, "POST", "/register", "", `{"username": "mallory", "password": "secret", "role": "admin"}`)
+
+	var first, mallory models.User
+	db.Where("username = ?", "first").First(&first)
+	db.Where("username = ?", "mallory").First(&mallory)
+	if first.Role != "admin" || mallory.Role != "user" {
+		t.Fatalf("Expected only the first user to be admin, got %s and %s", first.Role, mallory.Role)
+	}
+
+	for _, mode := range []string{RegistrationInviteOnly, RegistrationClosed} {
+		RegistrationMode = mode
+		if w := doJSON(r, "POST", "/register", "", `{"username": "late", "password": "secret"}`); w.Code != http.StatusForbidden {
+			t.Fatalf("Expected 403 in %s mode, got %d", mode, w.Code)
+		}
+	}
+
+	if err := CreateAdmin(db, "ops", "secret", ""); err != nil {
+		t.Fatal(err)
+	}
+	var ops models.User
+	db.Where("username = ?", "ops").First(&ops)
+	if ops.Role != "admin" {
+		t.Fatalf("Expected a CLI-created admin, got %s", ops.Role)
+	}
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"go_task_api/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var ChecklistDB *gorm.DB
+
+func InitChecklist(db *gorm.DB) {
+	ChecklistDB = db
+}
+
+// @Summary Get the checklist of a task
+// @Tags Checklists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {array} models.ChecklistItem
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/checklist [get]
+func GetChecklist(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var task models.Task
+	if err := ChecklistDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var items []models.ChecklistItem
+	ChecklistDB.Where("task_id = ?", task.ID).Order("position").Find(&items)
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary Add an item to the end of a task's checklist
+// @Tags Checklists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param item body models.ChecklistItemRequest true "Checklist item"
+// @Success 201 {object} models.ChecklistItem
+// @Failure 400,404 {object} map[string]string
+// @Router /tasks/{id}/checklist [post]
+func AddChecklistItem(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var task models.Task
+	if err := ChecklistDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var input models.ChecklistItemRequest
+	if err := c.BindJSON(&input); err != nil || strings.TrimSpace(input.Text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Text is required"})
+		return
+	}
+
+	var maxPosition *int
+	ChecklistDB.Model(&models.ChecklistItem{}).Where("task_id = ?", task.ID).
+		Select("MAX(position)").Scan(&maxPosition)
+
+	item := models.ChecklistItem{TaskID: task.ID, Text: strings.TrimSpace(input.Text)}
+	if maxPosition != nil {
+		item.Position = *maxPosition + 1
+	}
+	ChecklistDB.Create(&item)
+	c.JSON(http.StatusCreated, item)
+}
+
+// @Summary Reorder a task's checklist
+// @Tags Checklists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param order body models.ReorderChecklistRequest true "All item IDs in their new order"
+// @Success 200 {array} models.ChecklistItem
+// @Failure 400,404 {object} map[string]string
+// @Router /tasks/{id}/checklist [put]
+func ReorderChecklist(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var task models.Task
+	if err := ChecklistDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var input models.ReorderChecklistRequest
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err := reorder(ChecklistDB, &models.ChecklistItem{}, input.ItemIDs, "task_id = ?", task.ID)
+	if errors.Is(err, errIncompleteOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "item_ids must list every checklist item exactly once"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder checklist"})
+		return
+	}
+
+	var items []models.ChecklistItem
+	ChecklistDB.Where("task_id = ?", task.ID).Order("position").Find(&items)
+	c.JSON(http.StatusOK, items)
+}
+
+var errIncompleteOrder = errors.New("the new order must list every item exactly once")
+
+// reorder numbers the rows of model matching query in the order of ids, in
+// one transaction. ids must list every matching row exactly once.
+func reorder(db *gorm.DB, model interface{}, ids []uint, query string, args ...interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(model).Where(query, args...).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(ids) != len(existing) || len(uniqueIDs(ids)) != len(existing) {
+			return errIncompleteOrder
+		}
+		known := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			known[id] = true
+		}
+		for _, id := range ids {
+			if !known[id] {
+				return errIncompleteOrder
+			}
+		}
+
+		for position, id := range ids {
+			if err := tx.Model(model).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// @Summary Toggle a checklist item between checked and unchecked
+// @Tags Checklists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param item_id path int true "Checklist item ID"
+// @Success 200 {object} models.ChecklistItem
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/checklist/{item_id}/toggle [post]
+func ToggleChecklistItem(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var task models.Task
+	if err := ChecklistDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var item models.ChecklistItem
+	if err := ChecklistDB.Where("id = ? AND task_id = ?", c.Param("item_id"), task.ID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return
+	}
+
+	item.Checked = !item.Checked
+	ChecklistDB.Model(&item).Update("checked", item.Checked)
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Delete a checklist item
+// @Tags Checklists
+// @Security BearerAuth
+// @Param id path int true "Task ID"
+// @Param item_id path int true "Checklist item ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/checklist/{item_id} [delete]
+func DeleteChecklistItem(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var task models.Task
+	if err := ChecklistDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var item models.ChecklistItem
+	if err := ChecklistDB.Where("id = ? AND task_id = ?", c.Param("item_id"), task.ID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return
+	}
+
+	ChecklistDB.Delete(&item)
+	c.Status(http.StatusNoContent)
+}
+
+// attachChecklistProgress fills in ChecklistProgress for every task that has a checklist.
+func attachChecklistProgress(db *gorm.DB, tasks []models.Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	ids := make([]uint, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	var rows []struct {
+		TaskID uint
+		Done   int
+		Total  int
+	}
+	db.Model(&models.ChecklistItem{}).
+		Select("task_id, SUM(CASE WHEN checked THEN 1 ELSE 0 END) AS done, COUNT(*) AS total").
+		Where("task_id IN ?", ids).
+		Group("task_id").
+		Scan(&rows)
+
+	progress := make(map[uint]*models.ChecklistProgress, len(rows))
+	for _, row := range rows {
+		progress[row.TaskID] = &models.ChecklistProgress{Done: row.Done, Total: row.Total}
+	}
+	for i := range tasks {
+		tasks[i].ChecklistProgress = progress[tasks[i].ID]
+	}
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupChecklistTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitChecklist(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.GET("/tasks", GetTasks)
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/tasks/:id/checklist", AddChecklistItem)
+		auth.PUT("/tasks/:id/checklist", ReorderChecklist)
+		auth.POST("/tasks/:id/checklist/:item_id/toggle", ToggleChecklistItem)
+	}
+	return r
+}
+
+func doJSON(r *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestChecklistProgressInTaskList(t *testing.T) {
+	r := setupChecklistTestEnv()
+	token := registerAndLogin(r, t)
+
+	if w := doJSON(r, "POST", "/tasks", token, `{"title": "Groceries", "status": "todo"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d", w.Code)
+	}
+	for _, text := range []string{"milk", "eggs", "bread"} {
+		if w := doJSON(r, "POST", "/tasks/1/checklist", token, `{"text": "`+text+`"}`); w.Code != http.StatusCreated {
+			t.Fatalf("Expected 201 Created, got %d", w.Code)
+		}
+	}
+
+	if w := doJSON(r, "POST", "/tasks/1/checklist/2/toggle", token, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", w.Code)
+	}
+
+	w := doJSON(r, "PUT", "/tasks/1/checklist", token, `{"item_ids": [3, 1, 2]}`)
+	var items []models.ChecklistItem
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil || len(items) != 3 {
+		t.Fatalf("Unexpected reorder response: %s", w.Body.String())
+	}
+	if items[0].ID != 3 || items[2].ID != 2 || !items[2].Checked {
+		t.Fatalf("Unexpected checklist order: %+v", items)
+	}
+
+	if w := doJSON(r, "PUT", "/tasks/1/checklist", token, `{"item_ids": [3, 1]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for partial reorder, got %d", w.Code)
+	}
+
+	w = doJSON(r, "GET", "/tasks", token, "")
+	var tasks []models.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil || len(tasks) != 1 {
+		t.Fatalf("Unexpected tasks response: %s", w.Body.String())
+	}
+	p := tasks[0].ChecklistProgress
+	if p == nil || p.Done != 1 || p.Total != 3 {
+		t.Fatalf("Expected progress 1/3, got %+v", p)
+	}
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"go_task_api/events"
+	"go_task_api/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var CommentDB *gorm.DB
+
+func InitComment(db *gorm.DB) {
+	CommentDB = db
+}
+
+// @Summary List the comments on a task
+// @Tags Comments
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {array} models.Comment
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/comments [get]
+func GetComments(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	task, err := findVisibleTask(CommentDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var comments []models.Comment
+	CommentDB.Where("task_id = ?", task.ID).Order("created_at, id").Find(&comments)
+	c.JSON(http.StatusOK, comments)
+}
+
+// @Summary Comment on a task
+// @Description Commenting also makes you watch the task.
+// @Tags Comments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param comment body models.CommentRequest true "Comment"
+// @Success 201 {object} models.Comment
+// @Failure 400,404 {object} map[string]string
+// @Router /tasks/{id}/comments [post]
+func CreateComment(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	task, err := findVisibleTask(CommentDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var input models.CommentRequest
+	if err := c.BindJSON(&input); err != nil || strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body is required"})
+		return
+	}
+
+	comment := models.Comment{TaskID: task.ID, UserID: userID, Body: strings.TrimSpace(input.Body)}
+	CommentDB.Create(&comment)
+	watchTask(CommentDB, userID, task.ID)
+	events.Publish(events.Event{
+		Type:      events.CommentCreated,
+		ActorID:   userID,
+		ProjectID: task.ProjectID,
+		TaskID:    task.ID,
+		Data:      comment,
+	})
+	if mentioned := mentionedUsers(CommentDB, comment.Body, task, userID); len(mentioned) > 0 {
+		events.Publish(events.Event{
+			Type:       events.CommentMentioned,
+			ActorID:    userID,
+			ProjectID:  task.ProjectID,
+			TaskID:     task.ID,
+			Data:       comment,
+			Recipients: mentioned,
+		})
+	}
+	c.JSON(http.StatusCreated, comment)
+}
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"go_task_api/events"
+	"go_task_api/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var CopyDB *gorm.DB
+
+func InitCopy(db *gorm.DB) {
+	CopyDB = db
+}
+
+// @Summary Clone a project
+// @Description Copies the project, its custom field definitions and its tasks in a single transaction.
+// @Tags Projects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param input body models.CloneProjectRequest false "New name and what to copy"
+// @Success 201 {object} models.CopyResult
+// @Failure 400,404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /projects/{id}/clone [post]
+func CloneProject(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := CopyDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	input := models.CloneProjectRequest{CopyOptions: models.DefaultCopyOptions()}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+	if input.Attachments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Copying attachments is not supported"})
+		return
+	}
+	if input.Name == "" {
+		input.Name = project.Name + " (copy)"
+	}
+
+	result := models.CopyResult{TaskIDs: map[uint]uint{}}
+	var cp *taskCopier
+	err := CopyDB.Transaction(func(tx *gorm.DB) error {
+		clone := models.Project{Name: input.Name, UserID: userID}
+		if err := tx.Create(&clone).Error; err != nil {
+			return err
+		}
+		result.ProjectID = clone.ID
+		if err := watchProject(tx, userID, clone.ID); err != nil {
+			return err
+		}
+
+		cp = &taskCopier{tx: tx, userID: userID, opts: input.CopyOptions, taskIDs: result.TaskIDs}
+		if input.CustomFields {
+			cp.fieldIDs = map[uint]uint{}
+			var fields []models.CustomField
+			tx.Where("project_id = ?", project.ID).Find(&fields)
+			for _, field := range fields {
+				oldID := field.ID
+				field.ID = 0
+				field.ProjectID = clone.ID
+				if err := tx.Create(&field).Error; err != nil {
+					return err
+				}
+				cp.fieldIDs[oldID] = field.ID
+			}
+		}
+
+		var tasks []models.Task
+		tx.Where("project_id = ? AND user_id = ? AND parent_id IS NULL", project.ID, userID).Order("id").Find(&tasks)
+		for _, task := range tasks {
+			if _, err := cp.copy(task, clone.ID, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone project"})
+		return
+	}
+
+	publishProjectEvent(userID, models.Project{ID: result.ProjectID, Name: input.Name, UserID: userID})
+	cp.publish()
+	c.JSON(http.StatusCreated, result)
+}
+
+// @Summary Duplicate a task
+// @Description The copy is placed at the bottom of the same board column, next to the original.
+// @Tags Tasks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param input body models.DuplicateTaskRequest false "New title and what to copy"
+// @Success 201 {object} models.CopyResult
+// @Failure 400,404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tasks/{id}/duplicate [post]
+func DuplicateTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var task models.Task
+	if err := CopyDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	input := models.DuplicateTaskRequest{CopyOptions: models.DefaultCopyOptions()}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+	if input.Attachments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Copying attachments is not supported"})
+		return
+	}
+
+	result := models.CopyResult{TaskIDs: map[uint]uint{}}
+	var cp *taskCopier
+	err := CopyDB.Transaction(func(tx *gorm.DB) error {
+		source := task
+		if input.Title != "" {
+			source.Title = input.Title
+		}
+		source.Position = nextPosition(tx, userID, task.ProjectID, task.Status)
+
+		cp = &taskCopier{tx: tx, userID: userID, opts: input.CopyOptions, taskIDs: result.TaskIDs}
+		copied, err := cp.copy(source, task.ProjectID, task.ParentID)
+		if err != nil {
+			return err
+		}
+		result.TaskID = copied.ID
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to duplicate task"})
+		return
+	}
+
+	cp.publish()
+	c.JSON(http.StatusCreated, result)
+}
+
+// taskCopier copies tasks and, depending on opts, their tags, checklists,
+// custom field values, comments and subtasks within one transaction. The
+// user watches every copy, like a task they created.
+type taskCopier struct {
+	tx     *gorm.DB
+	userID uint
+	opts   models.CopyOptions
+	// fieldIDs maps custom fields to their copies; nil keeps values on the same fields
+	fieldIDs map[uint]uint
+	taskIDs  map[uint]uint
+	created  []models.Task
+}
+
+// publish announces the copies once the transaction has committed.
+func (cp *taskCopier) publish() {
+	attachCustomFields(CopyDB, cp.created)
+	for _, task := range cp.created {
+		publishTaskEvent(events.TaskCreated, cp.userID, task)
+	}
+}
+
+func (cp *taskCopier) copy(src models.Task, projectID uint, parentID *uint) (*models.Task, error) {
+	if _, done := cp.taskIDs[src.ID]; done {
+		return nil, nil
+	}
+
+	task := models.Task{
+		Title:       src.Title,
+		Description: src.Description,
+		Status:      src.Status,
+		Position:    src.Position,
+		DueDate:     src.DueDate,
+		ProjectID:   projectID,
+		ParentID:    parentID,
+		UserID:      cp.userID,
+	}
+	if cp.opts.Tags {
+		if err := cp.tx.Model(&src).Association("Tags").Find(&task.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if err := cp.tx.Create(&task).Error; err != nil {
+		return nil, err
+	}
+	cp.taskIDs[src.ID] = task.ID
+	if err := watchTask(cp.tx, cp.userID, task.ID); err != nil {
+		return nil, err
+	}
+
+	if cp.opts.Checklists {
+		var items []models.ChecklistItem
+		cp.tx.Where("task_id = ?", src.ID).Find(&items)
+		for _, item := range items {
+			item.ID = 0
+			item.TaskID = task.ID
+			if err := cp.tx.Create(&item).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cp.opts.CustomFields {
+		var values []models.CustomFieldValue
+		cp.tx.Where("task_id = ?", src.ID).Find(&values)
+		for _, value := range values {
+			if cp.fieldIDs != nil {
+				fieldID, ok := cp.fieldIDs[value.FieldID]
+				if !ok {
+					continue
+				}
+				value.FieldID = fieldID
+			}
+			value.ID = 0
+			value.TaskID = task.ID
+			if err := cp.tx.Create(&value).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cp.opts.Comments {
+		var comments []models.Comment
+		cp.tx.Where("task_id = ?", src.ID).Order("id").Find(&comments)
+		for _, comment := range comments {
+			comment.ID = 0
+			comment.TaskID = task.ID
+			if err := cp.tx.Create(&comment).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cp.opts.Subtasks {
+		var children []models.Task
+		cp.tx.Where("parent_id = ? AND user_id = ?", src.ID, cp.userID).Order("id").Find(&children)
+		for _, child := range children {
+			if _, err := cp.copy(child, projectID, &task.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cp.created = append(cp.created, task)
+	return &task, nil
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/events"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupCopyTestEnv() (*gin.Engine, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitTag(db)
+	InitChecklist(db)
+	InitCustomField(db)
+	InitComment(db)
+	InitCopy(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+	r.POST("/tags", CreateTag)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/tasks/:id/checklist", AddChecklistItem)
+		auth.POST("/tasks/:id/comments", CreateComment)
+		auth.POST("/tasks/:id/duplicate", DuplicateTask)
+		auth.POST("/projects", CreateProject)
+		auth.POST("/projects/:id/fields", CreateCustomField)
+		auth.POST("/projects/:id/clone", CloneProject)
+	}
+	return r, db
+}
+
+func TestCloneProjectAndDuplicateTask(t *testing.T) {
+	r, db := setupCopyTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/tags", token, `{"name": "urgent"}`)
+	doJSON(r, "POST", "/projects", token, `{"name": "Release"}`)
+	doJSON(r, "POST", "/projects/1/fields", token, `{"name": "points", "type": "number"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Ship", "project_id": 1, "tag_ids": [1], "custom_fields": {"points": 5}}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Changelog", "project_id": 1, "parent_id": 1}`)
+	doJSON(r, "POST", "/tasks/1/checklist", token, `{"text": "tag release"}`)
+	doJSON(r, "POST", "/tasks/1/comments", token, `{"body": "Waiting on QA"}`)
+
+	w := doJSON(r, "POST", "/projects/1/clone", token, `{"name": "Release 2", "tags": false}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d: %s", w.Code, w.Body.String())
+	}
+	var result models.CopyResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.ProjectID != 2 || len(result.TaskIDs) != 2 {
+		t.Fatalf("Unexpected clone result: %+v", result)
+	}
+
+	var ship models.Task
+	db.Preload("Tags").First(&ship, result.TaskIDs[1])
+	if ship.ProjectID != 2 || len(ship.Tags) != 0 {
+		t.Fatalf("Expected untagged copy in the new project, got %+v", ship)
+	}
+	var changelog models.Task
+	db.First(&changelog, result.TaskIDs[2])
+	if changelog.ParentID == nil || *changelog.ParentID != ship.ID {
+		t.Fatalf("Expected subtask under the copied parent, got %+v", changelog.ParentID)
+	}
+	var value models.CustomFieldValue
+	if err := db.Where("task_id = ?", ship.ID).First(&value).Error; err != nil || value.FieldID != 2 {
+		t.Fatalf("Expected custom field value on the cloned field, got %+v", value)
+	}
+
+	w = doJSON(r, "POST", "/tasks/1/duplicate", token, `{"subtasks": false}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d", w.Code)
+	}
+	result = models.CopyResult{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if len(result.TaskIDs) != 1 {
+		t.Fatalf("Expected only the task itself to be copied, got %+v", result)
+	}
+
+	var items int64
+	db.Model(&models.ChecklistItem{}).Where("task_id = ?", result.TaskID).Count(&items)
+	if items != 1 {
+		t.Fatalf("Expected checklist to be copied, got %d items", items)
+	}
+
+	var comments []models.Comment
+	db.Where("task_id = ?", result.TaskID).Find(&comments)
+	if len(comments) != 1 || comments[0].Body != "Waiting on QA" {
+		t.Fatalf("Expected comments to be copied, got %+v", comments)
+	}
+
+	var before int64
+	db.Model(&models.Comment{}).Count(&before)
+	if w := doJSON(r, "POST", "/tasks/1/duplicate", token, `{"comments": false}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d", w.Code)
+	}
+	db.Model(&models.Comment{}).Count(&items)
+	if items != before {
+		t.Fatalf("Expected comments to be left behind, got %d comments", items)
+	}
+	if w := doJSON(r, "POST", "/tasks/1/duplicate", token, `{"attachments": true}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for attachments, got %d", w.Code)
+	}
+}
+
+func TestCopiesAreWatchedAndAnnounced(t *testing.T) {
+	r, db := setupCopyTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Release"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Ship", "project_id": 1}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Changelog", "project_id": 1, "parent_id": 1}`)
+
+	created := func(since uint64) []uint {
+		published, _ := events.Since(since)
+		var ids []uint
+		for _, e := range published {
+			if e.Type == events.TaskCreated {
+				ids = append(ids, e.TaskID)
+			}
+		}
+		return ids
+	}
+
+	mark := events.LastID()
+	var result models.CopyResult
+	json.Unmarshal(doJSON(r, "POST", "/projects/1/clone", token, "").Body.Bytes(), &result)
+	if ids := created(mark); len(ids) != 2 {
+		t.Fatalf("Expected an event per cloned task, got %v", ids)
+	}
+	var watches int64
+	db.Model(&models.Watch{}).Where("project_id = ? OR task_id IN ?", result.ProjectID, []uint{result.TaskIDs[1], result.TaskIDs[2]}).Count(&watches)
+	if watches != 3 {
+		t.Fatalf("Expected the cloner to watch the project and both tasks, got %d watches", watches)
+	}
+
+	mark = events.LastID()
+	result = models.CopyResult{}
+	json.Unmarshal(doJSON(r, "POST", "/tasks/1/duplicate", token, `{"subtasks": false}`).Body.Bytes(), &result)
+	if ids := created(mark); len(ids) != 1 || ids[0] != result.TaskID {
+		t.Fatalf("Expected an event for the duplicate, got %v", ids)
+	}
+	if err := db.Where("user_id = ? AND task_id = ?", 1, result.TaskID).First(&models.Watch{}).Error; err != nil {
+		t.Fatalf("Expected the duplicate to be watched: %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"fmt"
+	"go_task_api/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var CustomFieldDB *gorm.DB
+
+func InitCustomField(db *gorm.DB) {
+	CustomFieldDB = db
+}
+
+// @Summary Define a custom field on a project
+// @Tags Custom Fields
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param field body models.CustomField true "Field definition (type: text, number, date, enum or user)"
+// @Success 201 {object} models.CustomField
+// @Failure 400,404,409 {object} map[string]string
+// @Router /projects/{id}/fields [post]
+func CreateCustomField(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := CustomFieldDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var field models.CustomField
+	if err := c.BindJSON(&field); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	field.ID = 0
+	field.ProjectID = project.ID
+	field.Name = strings.TrimSpace(field.Name)
+
+	if field.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	switch field.Type {
+	case models.FieldTypeText, models.FieldTypeNumber, models.FieldTypeDate, models.FieldTypeUser:
+		field.Options = nil
+	case models.FieldTypeEnum:
+		if len(field.Options) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Enum fields need at least one option"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Type must be one of text, number, date, enum or user"})
+		return
+	}
+
+	var existing models.CustomField
+	if err := CustomFieldDB.Where("project_id = ? AND name = ?", project.ID, field.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Project already has a field with this name"})
+		return
+	}
+
+	CustomFieldDB.Create(&field)
+	c.JSON(http.StatusCreated, field)
+}
+
+// @Summary List a project's custom fields
+// @Tags Custom Fields
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} models.CustomField
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/fields [get]
+func GetCustomFields(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := visibleProjects(CustomFieldDB, userID).Where("id = ?", c.Param("id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var fields []models.CustomField
+	CustomFieldDB.Where("project_id = ?", project.ID).Order("id").Find(&fields)
+	c.JSON(http.StatusOK, fields)
+}
+
+// @Summary Delete a custom field and all of its values
+// @Tags Custom Fields
+// @Security BearerAuth
+// @Param id path int true "Project ID"
+// @Param field_id path int true "Field ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /projects/{id}/fields/{field_id} [delete]
+func DeleteCustomField(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := CustomFieldDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var field models.CustomField
+	if err := CustomFieldDB.Where("id = ? AND project_id = ?", c.Param("field_id"), project.ID).First(&field).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		return
+	}
+
+	err := CustomFieldDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("field_id = ?", field.ID).Delete(&models.CustomFieldValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&field).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete field"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// validateCustomFields checks the submitted values against the project's
+// field definitions and returns them in canonical form, keyed by field ID.
+// A nil value clears the field. When creating, required fields must be set.
+func validateCustomFields(db *gorm.DB, projectID uint, input map[string]interface{}, creating bool) (map[uint]*string, error) {
+	var fields []models.CustomField
+	if projectID != 0 {
+		db.Where("project_id = ?", projectID).Find(&fields)
+	}
+	if len(input) > 0 && projectID == 0 {
+		return nil, fmt.Errorf("custom fields require the task to belong to a project")
+	}
+
+	byName := make(map[string]models.CustomField, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+
+	values := make(map[uint]*string, len(input))
+	for name, raw := range input {
+		field, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", name)
+		}
+		if raw == nil {
+			if field.Required {
+				return nil, fmt.Errorf("custom field %q is required", name)
+			}
+			values[field.ID] = nil
+			continue
+		}
+		value, err := normalizeFieldValue(db, field, raw)
+		if err != nil {
+			return nil, err
+		}
+		values[field.ID] = &value
+	}
+
+	if creating {
+		for _, field := range fields {
+			if field.Required && values[field.ID] == nil {
+				return nil, fmt.Errorf("custom field %q is required", field.Name)
+			}
+		}
+	}
+	return values, nil
+}
+
+// normalizeFieldValue converts a JSON value into the canonical text stored for the field's type.
+func normalizeFieldValue(db *gorm.DB, field models.CustomField, raw interface{}) (string, error) {
+	invalid := fmt.Errorf("invalid value for %s field %q", field.Type, field.Name)
+
+	switch field.Type {
+	case models.FieldTypeNumber, models.FieldTypeUser:
+		var n float64
+		switch v := raw.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return "", invalid
+			}
+			n = parsed
+		default:
+			return "", invalid
+		}
+		if field.Type == models.FieldTypeNumber {
+			return strconv.FormatFloat(n, 'f', -1, 64), nil
+		}
+		var user models.User
+		if n <= 0 || n != float64(uint(n)) || db.First(&user, uint(n)).Error != nil {
+			return "", fmt.Errorf("custom field %q must reference an existing user", field.Name)
+		}
+		return strconv.FormatUint(uint64(user.ID), 10), nil
+
+	case models.FieldTypeDate:
+		s, ok := raw.(string)
+		if !ok {
+			return "", invalid
+		}
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return "", invalid
+		}
+		return d.Format("2006-01-02"), nil
+
+	case models.FieldTypeEnum:
+		s, ok := raw.(string)
+		if !ok {
+			return "", invalid
+		}
+		for _, option := range field.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return "", fmt.Errorf("custom field %q must be one of %s", field.Name, strings.Join(field.Options, ", "))
+
+	default:
+		s, ok := raw.(string)
+		if !ok {
+			return "", invalid
+		}
+		return s, nil
+	}
+}
+
+// storeCustomFields writes validated values for a task, deleting cleared ones.
+func storeCustomFields(tx *gorm.DB, taskID uint, values map[uint]*string) error {
+	for fieldID, value := range values {
+		if value == nil {
+			if err := tx.Where("task_id = ? AND field_id = ?", taskID, fieldID).Delete(&models.CustomFieldValue{}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		row := models.CustomFieldValue{TaskID: taskID, FieldID: fieldID, Value: *value}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "task_id"}, {Name: "field_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		}).Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachCustomFields fills in CustomFields for every task that has values.
+func attachCustomFields(db *gorm.DB, tasks []models.Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	ids := make([]uint, len(tasks))
+	index := make(map[uint]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+		index[task.ID] = i
+	}
+
+	var rows []struct {
+		TaskID uint
+		Name   string
+		Type   string
+		Value  string
+	}
+	db.Table("custom_field_values").
+		Select("custom_field_values.task_id, custom_fields.name, custom_fields.type, custom_field_values.value").
+		Joins("JOIN custom_fields ON custom_fields.id = custom_field_values.field_id").
+		Where("custom_field_values.task_id IN ?", ids).
+		Scan(&rows)
+
+	for _, row := range rows {
+		task := &tasks[index[row.TaskID]]
+		if task.CustomFields == nil {
+			task.CustomFields = make(map[string]interface{})
+		}
+		task.CustomFields[row.Name] = decodeFieldValue(row.Type, row.Value)
+	}
+}
+
+func decodeFieldValue(fieldType, value string) interface{} {
+	switch fieldType {
+	case models.FieldTypeNumber:
+		n, _ := strconv.ParseFloat(value, 64)
+		return n
+	case models.FieldTypeUser:
+		n, _ := strconv.ParseUint(value, 10, 64)
+		return uint(n)
+	}
+	return value
+}
+
+// customFieldValueSQL selects a task's value for the custom field with the given name.
+const customFieldValueSQL = "(SELECT custom_field_values.value FROM custom_field_values " +
+	"JOIN custom_fields ON custom_fields.id = custom_field_values.field_id " +
+	"WHERE custom_field_values.task_id = tasks.id AND custom_fields.name = ?)"
+
+// customFieldNumberMatchSQL matches a task whose field with the given name
+// equals a number, compared numerically for number fields and as text otherwise.
+const customFieldNumberMatchSQL = "EXISTS (SELECT 1 FROM custom_field_values " +
+	"JOIN custom_fields ON custom_fields.id = custom_field_values.field_id " +
+	"WHERE custom_field_values.task_id = tasks.id AND custom_fields.name = ? AND " +
+	"CASE WHEN custom_fields.type = ? THEN CAST(custom_field_values.value AS REAL) = ? " +
+	"ELSE custom_field_values.value = ? END)"
+
+// filterByCustomFields applies ?cf.<name>=<value> filters from the query string.
+// Number fields match by value, so 5 finds a stored 5.0.
+func filterByCustomFields(c *gin.Context, query *gorm.DB) *gorm.DB {
+	for key, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(key, "cf.")
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		if n, err := strconv.ParseFloat(values[0], 64); err == nil {
+			query = query.Where(customFieldNumberMatchSQL, name, models.FieldTypeNumber, n, values[0])
+		} else {
+			query = query.Where(customFieldValueSQL+" = ?", name, values[0])
+		}
+	}
+	return query
+}
+
+// orderByCustomField sorts by a custom field's value, numerically for number
+// and user fields. Without a project the name must mean the same type of field
+// in every one of the user's projects, otherwise it is ambiguous.
+func orderByCustomField(db *gorm.DB, query *gorm.DB, userID uint, projectID, name, order string) (*gorm.DB, error) {
+	fields := db.Joins("JOIN projects ON projects.id = custom_fields.project_id").
+		Where("projects.user_id = ? AND custom_fields.name = ?", userID, name)
+	if projectID != "" {
+		fields = fields.Where("custom_fields.project_id = ?", projectID)
+	}
+	var types []string
+	fields.Model(&models.CustomField{}).Distinct().Pluck("custom_fields.type", &types)
+	if len(types) > 1 {
+		return nil, fmt.Errorf("custom field %q has different types across projects, filter by project_id to sort by it", name)
+	}
+
+	sql := customFieldValueSQL
+	if len(types) == 1 && (types[0] == models.FieldTypeNumber || types[0] == models.FieldTypeUser) {
+		sql = "CAST(" + sql + " AS REAL)"
+	}
+	return query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                sql + " " + order,
+		Vars:               []interface{}{name},
+		WithoutParentheses: true,
+	}}), nil
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupCustomFieldTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitCustomField(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.GET("/tasks", GetTasks)
+		auth.POST("/tasks", CreateTask)
+		auth.PUT("/tasks/:id", UpdateTask)
+		auth.POST("/projects", CreateProject)
+		auth.POST("/projects/:id/fields", CreateCustomField)
+	}
+	return r
+}
+
+func TestCustomFieldsValidateFilterAndSort(t *testing.T) {
+	r := setupCustomFieldTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Support"}`)
+	if w := doJSON(r, "POST", "/projects/1/fields", token, `{"name": "severity", "type": "enum", "options": ["low", "high"], "required": true}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(r, "POST", "/projects/1/fields", token, `{"name": "points", "type": "number"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d", w.Code)
+	}
+
+	if w := doJSON(r, "POST", "/tasks", token, `{"title": "a", "project_id": 1}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for missing required field, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/tasks", token, `{"title": "a", "project_id": 1, "custom_fields": {"severity": "urgent"}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for invalid enum value, got %d", w.Code)
+	}
+
+	for _, payload := range []string{
+		`{"title": "a", "project_id": 1, "custom_fields": {"severity": "high", "points": 8}}`,
+		`{"title": "b", "project_id": 1, "custom_fields": {"severity": "low", "points": 13}}`,
+		`{"title": "c", "project_id": 1, "custom_fields": {"severity": "high", "points": 2}}`,
+	} {
+		if w := doJSON(r, "POST", "/tasks", token, payload); w.Code != http.StatusCreated {
+			t.Fatalf("Expected 201 Created, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	if w := doJSON(r, "PUT", "/tasks/2", token, `{"custom_fields": {"points": "not a number"}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for invalid number, got %d", w.Code)
+	}
+
+	w := doJSON(r, "GET", "/tasks?cf.severity=high&sort=cf.points&order=asc", token, "")
+	var tasks []models.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "c" || tasks[1].Title != "a" {
+		t.Fatalf("Unexpected filtered tasks: %+v", tasks)
+	}
+	if tasks[0].CustomFields["points"] != float64(2) {
+		t.Fatalf("Expected custom field values in response, got %+v", tasks[0].CustomFields)
+	}
+
+	// Numbers match by value, not by how they are written
+	w = doJSON(r, "GET", "/tasks?cf.points=8.0", token, "")
+	tasks = nil
+	json.Unmarshal(w.Body.Bytes(), &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "a" {
+		t.Fatalf("Expected 8.0 to match the task with 8 points, got %+v", tasks)
+	}
+
+	// Another project uses the same name for a text field
+	doJSON(r, "POST", "/projects", token, `{"name": "Docs"}`)
+	doJSON(r, "POST", "/projects/2/fields", token, `{"name": "points", "type": "text"}`)
+	if w := doJSON(r, "GET", "/tasks?sort=cf.points", token, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an ambiguous field name, got %d", w.Code)
+	}
+	w = doJSON(r, "GET", "/tasks?project_id=1&sort=cf.points&order=desc", token, "")
+	tasks = nil
+	json.Unmarshal(w.Body.Bytes(), &tasks)
+	if len(tasks) != 3 || tasks[0].Title != "b" || tasks[2].Title != "c" {
+		t.Fatalf("Expected numeric order within the project, got %+v", tasks)
+	}
+}
@@ -0,0 +1,522 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go_task_api/events"
+	"go_task_api/models"
+	"go_task_api/utils"
+	htmltemplate "html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var EmailDB *gorm.DB
+
+// Mailer delivers notification emails; email is disabled while it is nil
+var Mailer utils.Mailer
+
+// DigestHour is the local hour after which the daily digest goes out (DIGEST_HOUR, default 8)
+var DigestHour = 8
+
+// DueSoonWindow is how far ahead a due date counts as "due soon"
+var DueSoonWindow = 24 * time.Hour
+
+// EmailVerificationTTL is how long an email verification token stays valid
+var EmailVerificationTTL = 24 * time.Hour
+
+var errInvalidVerification = errors.New("Invalid or expired verification token")
+
+// emailByDefault lists the event types that are emailed unless the user opts out
+var emailByDefault = map[string]bool{
+	events.TaskAssigned:     true,
+	events.CommentMentioned: true,
+	events.TaskDueSoon:      true,
+	events.TaskReminder:     true,
+}
+
+var subscribeEmail sync.Once
+
+func InitEmail(db *gorm.DB, mailer utils.Mailer) {
+	EmailDB = db
+	Mailer = mailer
+	if hour, err := strconv.Atoi(os.Getenv("DIGEST_HOUR")); err == nil && hour >= 0 && hour < 24 {
+		DigestHour = hour
+	}
+	subscribeEmail.Do(func() {
+		events.Subscribe(emailRecipients)
+	})
+}
+
+// @Summary Get your email address and digest subscription
+// @Tags Notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.EmailSettings
+// @Router /me/email-settings [get]
+func GetEmailSettings(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var user models.User
+	if err := EmailDB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, models.EmailSettings{Email: user.Email, DailyDigest: user.DailyDigest, Verified: user.EmailVerifiedAt != nil})
+}
+
+// @Summary Set your email address and opt in or out of the daily digest
+// @Description A new address starts out unverified and is sent a verification token, valid for a day, to confirm with POST /email/verify. Saving an unverified address again sends a new token once the last one has expired.
+// @Tags Notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param settings body models.EmailSettings true "Email settings"
+// @Success 200 {object} models.EmailSettings
+// @Failure 400 {object} map[string]string
+// @Router /me/email-settings [put]
+func UpdateEmailSettings(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.EmailSettings
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if input.Email != "" {
+		// Only the bare address is kept, "Bob <bob@example.com>" can't be used as a recipient
+		addr, err := mail.ParseAddress(input.Email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+			return
+		}
+		input.Email = addr.Address
+	}
+	if input.DailyDigest && input.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The daily digest needs an email address"})
+		return
+	}
+
+	var user models.User
+	if err := EmailDB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	updates := map[string]interface{}{"email": input.Email, "daily_digest": input.DailyDigest}
+	changed := input.Email != user.Email
+	if changed {
+		updates["email_verified_at"] = nil
+		user.EmailVerifiedAt = nil
+	}
+	if err := EmailDB.Model(&user).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email settings"})
+		return
+	}
+
+	if user.Email != "" && user.EmailVerifiedAt == nil && (changed || !verificationPending(EmailDB, user)) {
+		if err := sendEmailVerification(EmailDB, user); err != nil {
+			log.Printf("email verification for user %d: %v", user.ID, err)
+		}
+	}
+	input.Verified = user.EmailVerifiedAt != nil
+	c.JSON(http.StatusOK, input)
+}
+
+// @Summary Confirm an email address with the emailed verification token
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param input body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /email/verify [post]
+func VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.BindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	err := EmailDB.Transaction(func(tx *gorm.DB) error {
+		var verification models.EmailVerification
+		if err := tx.Where("token_hash = ? AND expires_at > ?", utils.HashToken(req.Token), time.Now()).
+			First(&verification).Error; err != nil {
+			return errInvalidVerification
+		}
+		// The token only vouches for the address it was sent to
+		claimed := tx.Model(&models.User{}).Where("id = ? AND email = ?", verification.UserID, verification.Email).
+			Update("email_verified_at", time.Now())
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			return errInvalidVerification
+		}
+		return tx.Where("user_id = ?", verification.UserID).Delete(&models.EmailVerification{}).Error
+	})
+	if errors.Is(err, errInvalidVerification) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// sendEmailVerification replaces the user's outstanding verification tokens
+// with one for their current address and emails it there. Without a mailer
+// the address stays unverified.
+func sendEmailVerification(db *gorm.DB, user models.User) error {
+	if Mailer == nil {
+		return nil
+	}
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	verification := models.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(EmailVerificationTTL),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.EmailVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&verification).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := renderEmail("verify_email", emailData{User: user, VerificationToken: token, VerificationExpiresAt: verification.ExpiresAt})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	queueEmail(msg)
+	return nil
+}
+
+// verificationPending reports whether a token for the user's current address
+// is still valid, so saving the settings again doesn't send another one.
+func verificationPending(db *gorm.DB, user models.User) bool {
+	var count int64
+	db.Model(&models.EmailVerification{}).
+		Where("user_id = ? AND email = ? AND expires_at > ?", user.ID, user.Email, time.Now()).
+		Count(&count)
+	return count > 0
+}
+
+// StartEmailJobs checks for tasks that are due soon and sends daily digests
+// every interval, until the process exits.
+func StartEmailJobs(interval time.Duration) {
+	go func() {
+		for {
+			publishDueSoon(EmailDB, time.Now())
+			sendDigests(EmailDB, time.Now())
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// publishDueSoon announces every open task that is due within DueSoonWindow, once.
+func publishDueSoon(db *gorm.DB, now time.Time) {
+	var tasks []models.Task
+	db.Where("due_date > ? AND due_date <= ? AND due_soon_sent_at IS NULL AND status NOT IN ?",
+		now, now.Add(DueSoonWindow), models.ClosedStatuses).Find(&tasks)
+
+	for _, task := range tasks {
+		// Claim the task first so a concurrent run can't announce it twice
+		claimed := db.Model(&models.Task{}).Where("id = ? AND due_soon_sent_at IS NULL", task.ID).
+			Update("due_soon_sent_at", now)
+		if claimed.Error != nil || claimed.RowsAffected == 0 {
+			continue
+		}
+		events.Publish(events.Event{
+			Type:      events.TaskDueSoon,
+			ProjectID: task.ProjectID,
+			TaskID:    task.ID,
+			Data:      task,
+		})
+	}
+}
+
+// sendDigests emails opted-in users a summary of their overdue and due-today
+// tasks, once a day after DigestHour.
+func sendDigests(db *gorm.DB, now time.Time) {
+	if Mailer == nil || now.Hour() < DigestHour {
+		return
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+
+	var users []models.User
+	db.Where("daily_digest = ? AND email <> '' AND (last_digest_at IS NULL OR last_digest_at < ?)", true, today).Find(&users)
+
+	for _, user := range users {
+		claimed := db.Model(&models.User{}).
+			Where("id = ? AND (last_digest_at IS NULL OR last_digest_at < ?)", user.ID, today).
+			Update("last_digest_at", now)
+		if claimed.Error != nil || claimed.RowsAffected == 0 {
+			continue
+		}
+
+		mine := db.Where("(user_id = ? OR assignee_id = ?) AND status NOT IN ?", user.ID, user.ID, models.ClosedStatuses)
+		var overdue, dueToday []models.Task
+		mine.Session(&gorm.Session{}).Where("due_date < ?", today).Order("due_date").Find(&overdue)
+		mine.Session(&gorm.Session{}).Where("due_date >= ? AND due_date < ?", today, tomorrow).Order("due_date").Find(&dueToday)
+		if len(overdue) == 0 && len(dueToday) == 0 {
+			continue
+		}
+
+		data := emailData{User: user, Overdue: overdue, DueToday: dueToday}
+		msg, err := renderEmail("digest", data)
+		if err != nil {
+			log.Printf("rendering digest: %v", err)
+			continue
+		}
+		msg.To = user.Email
+		queueEmail(msg)
+	}
+}
+
+// emailRecipients emails everyone interested in an event that has an email
+// template, unless they turned email off for that event type.
+func emailRecipients(e events.Event) {
+	if Mailer == nil {
+		return
+	}
+	if _, ok := emailTemplates[e.Type]; !ok {
+		return
+	}
+	db := EmailDB
+	recipients := eventRecipients(db, e)
+	if len(recipients) == 0 {
+		return
+	}
+
+	var users []models.User
+	db.Where("id IN ? AND email <> ''", recipients).Find(&users)
+
+	data := emailData{Event: e}
+	db.First(&data.Actor, e.ActorID)
+	switch v := e.Data.(type) {
+	case models.Task:
+		data.Task = v
+	case models.Comment:
+		data.Comment = v
+		db.First(&data.Task, v.TaskID)
+	}
+
+	for _, user := range users {
+		if !wantsEmail(db, user.ID, e.Type) {
+			continue
+		}
+		data.User = user
+		msg, err := renderEmail(e.Type, data)
+		if err != nil {
+			log.Printf("rendering %s email: %v", e.Type, err)
+			continue
+		}
+		msg.To = user.Email
+		queueEmail(msg)
+	}
+}
+
+func wantsEmail(db *gorm.DB, userID uint, eventType string) bool {
+	var pref models.NotificationPreference
+	if err := db.Where("user_id = ? AND event_type = ?", userID, eventType).First(&pref).Error; err != nil {
+		return emailByDefault[eventType]
+	}
+	return pref.Email
+}
+
+// Emails are sent in the background by EmailWorkers goroutines, from a queue
+// holding up to EmailQueueSize messages. Emails arriving while the queue is
+// full are dropped rather than piling up.
+var (
+	EmailWorkers   = 4
+	EmailQueueSize = 256
+)
+
+var (
+	emailQueue        chan utils.MailMessage
+	startEmailWorkers sync.Once
+)
+
+// queueEmail hands msg to the email workers, starting them on first use.
+func queueEmail(msg utils.MailMessage) {
+	startEmailWorkers.Do(func() {
+		emailQueue = make(chan utils.MailMessage, EmailQueueSize)
+		for i := 0; i < EmailWorkers; i++ {
+			go func() {
+				for msg := range emailQueue {
+					deliver(msg)
+				}
+			}()
+		}
+	})
+	select {
+	case emailQueue <- msg:
+	default:
+		log.Printf("email queue full, dropping email to %s", msg.To)
+	}
+}
+
+func deliver(msg utils.MailMessage) {
+	if err := Mailer.Send(msg); err != nil {
+		log.Printf("sending email to %s: %v", msg.To, err)
+	}
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|\W)@(\w+)`)
+
+// mentionedUsers returns the users mentioned as @username in a comment who
+// can see the task, leaving out the author.
+func mentionedUsers(db *gorm.DB, body string, task models.Task, authorID uint) []uint {
+	var names []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		names = append(names, m[1])
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	db.Where("username IN ?", names).Find(&users)
+	var ids []uint
+	for _, user := range users {
+		if canSeeTask(db, task, user.ID) && user.ID != authorID {
+			ids = append(ids, user.ID)
+		}
+	}
+	return ids
+}
+
+type emailData struct {
+	Event      events.Event
+	Invitation models.Invitation
+	User       models.User
+	Actor      models.User
+	Task       models.Task
+	Comment    models.Comment
+	Overdue    []models.Task
+	DueToday   []models.Task
+
+	ResetToken     string
+	ResetExpiresAt time.Time
+
+	VerificationToken     string
+	VerificationExpiresAt time.Time
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+func newEmailTemplate(subject, text, html string) emailTemplate {
+	return emailTemplate{
+		subject: texttemplate.Must(texttemplate.New("subject").Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New("text").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New("html").Parse(html)),
+	}
+}
+
+var emailTemplates = map[string]emailTemplate{
+	events.TaskAssigned: newEmailTemplate(
+		`{{.Actor.Username}} assigned you "{{.Task.Title}}"`,
+		"Hi {{.User.Username}},\n\n{{.Actor.Username}} assigned you task #{{.Task.ID}} \"{{.Task.Title}}\".\n"+
+			"{{if .Task.DueDate}}It is due {{.Task.DueDate.Format \"Mon 2 Jan 15:04\"}}.\n{{end}}",
+		`<p>Hi {{.User.Username}},</p><p>{{.Actor.Username}} assigned you task #{{.Task.ID}} <strong>{{.Task.Title}}</strong>.</p>`+
+			`{{if .Task.DueDate}}<p>It is due {{.Task.DueDate.Format "Mon 2 Jan 15:04"}}.</p>{{end}}`,
+	),
+	events.CommentMentioned: newEmailTemplate(
+		`{{.Actor.Username}} mentioned you on "{{.Task.Title}}"`,
+		"Hi {{.User.Username}},\n\n{{.Actor.Username}} mentioned you on task #{{.Task.ID}} \"{{.Task.Title}}\":\n\n{{.Comment.Body}}\n",
+		`<p>Hi {{.User.Username}},</p><p>{{.Actor.Username}} mentioned you on task #{{.Task.ID}} <strong>{{.Task.Title}}</strong>:</p>`+
+			`<blockquote>{{.Comment.Body}}</blockquote>`,
+	),
+	events.TaskDueSoon: newEmailTemplate(
+		`"{{.Task.Title}}" is due soon`,
+		"Hi {{.User.Username}},\n\nTask #{{.Task.ID}} \"{{.Task.Title}}\" is due {{.Task.DueDate.Format \"Mon 2 Jan 15:04\"}}.\n",
+		`<p>Hi {{.User.Username}},</p><p>Task #{{.Task.ID}} <strong>{{.Task.Title}}</strong> is due {{.Task.DueDate.Format "Mon 2 Jan 15:04"}}.</p>`,
+	),
+	events.TaskReminder: newEmailTemplate(
+		`Reminder: "{{.Task.Title}}"`,
+		"Hi {{.User.Username}},\n\nThis is your reminder about task #{{.Task.ID}} \"{{.Task.Title}}\".\n"+
+			"{{if .Task.DueDate}}It is due {{.Task.DueDate.Format \"Mon 2 Jan 15:04\"}}.\n{{end}}",
+		`<p>Hi {{.User.Username}},</p><p>This is your reminder about task #{{.Task.ID}} <strong>{{.Task.Title}}</strong>.</p>`+
+			`{{if .Task.DueDate}}<p>It is due {{.Task.DueDate.Format "Mon 2 Jan 15:04"}}.</p>{{end}}`,
+	),
+	"invitation": newEmailTemplate(
+		`{{.Actor.Username}} invited you to the task manager`,
+		"Hi,\n\n{{.Actor.Username}} invited you to join. Sign up with this invitation token before {{.Invitation.ExpiresAt.Format \"Mon 2 Jan 15:04\"}}:\n\n{{.Invitation.Token}}\n",
+		`<p>Hi,</p><p>{{.Actor.Username}} invited you to join. Sign up with this invitation token before {{.Invitation.ExpiresAt.Format "Mon 2 Jan 15:04"}}:</p>`+
+			`<p><code>{{.Invitation.Token}}</code></p>`,
+	),
+	"password_reset": newEmailTemplate(
+		`Reset your password`,
+		"Hi {{.User.Username}},\n\nSomeone asked to reset your password. To choose a new one, use this token before {{.ResetExpiresAt.Format \"15:04 MST\"}}:\n\n{{.ResetToken}}\n\n"+
+			"If that wasn't you, ignore this email; your password stays the same.\n",
+		`<p>Hi {{.User.Username}},</p><p>Someone asked to reset your password. To choose a new one, use this token before {{.ResetExpiresAt.Format "15:04 MST"}}:</p>`+
+			`<p><code>{{.ResetToken}}</code></p><p>If that wasn't you, ignore this email; your password stays the same.</p>`,
+	),
+	"verify_email": newEmailTemplate(
+		`Confirm your email address`,
+		"Hi {{.User.Username}},\n\nTo confirm that this is your email address, use this token before {{.VerificationExpiresAt.Format \"Mon 2 Jan 15:04\"}}:\n\n{{.VerificationToken}}\n\n"+
+			"If you didn't add this address, ignore this email.\n",
+		`<p>Hi {{.User.Username}},</p><p>To confirm that this is your email address, use this token before {{.VerificationExpiresAt.Format "Mon 2 Jan 15:04"}}:</p>`+
+			`<p><code>{{.VerificationToken}}</code></p><p>If you didn't add this address, ignore this email.</p>`,
+	),
+	"digest": newEmailTemplate(
+		`Your tasks for today`,
+		"Hi {{.User.Username}},\n"+
+			"{{if .Overdue}}\nOverdue:\n{{range .Overdue}}- #{{.ID}} {{.Title}} (due {{.DueDate.Format \"2 Jan\"}})\n{{end}}{{end}}"+
+			"{{if .DueToday}}\nDue today:\n{{range .DueToday}}- #{{.ID}} {{.Title}}\n{{end}}{{end}}",
+		`<p>Hi {{.User.Username}},</p>`+
+			`{{if .Overdue}}<h3>Overdue</h3><ul>{{range .Overdue}}<li>#{{.ID}} {{.Title}} (due {{.DueDate.Format "2 Jan"}})</li>{{end}}</ul>{{end}}`+
+			`{{if .DueToday}}<h3>Due today</h3><ul>{{range .DueToday}}<li>#{{.ID}} {{.Title}}</li>{{end}}</ul>{{end}}`,
+	),
+}
+
+func renderEmail(name string, data emailData) (utils.MailMessage, error) {
+	tmpl, ok := emailTemplates[name]
+	if !ok {
+		return utils.MailMessage{}, fmt.Errorf("no email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return utils.MailMessage{}, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return utils.MailMessage{}, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return utils.MailMessage{}, err
+	}
+	return utils.MailMessage{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
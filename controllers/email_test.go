@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"go_task_api/utils"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []utils.MailMessage
+}
+
+func (m *fakeMailer) Send(msg utils.MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// waitFor polls until n messages were sent, since delivery is asynchronous
+func (m *fakeMailer) waitFor(t *testing.T, n int) []utils.MailMessage {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		m.mu.Lock()
+		sent := append([]utils.MailMessage(nil), m.sent...)
+		m.mu.Unlock()
+		if len(sent) >= n {
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d emails, got %d", n, len(m.sent))
+	return nil
+}
+
+// reset forgets the emails sent so far
+func (m *fakeMailer) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = nil
+}
+
+func setupEmailTestEnv(t *testing.T) (*gin.Engine, *gorm.DB, *fakeMailer) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitComment(db)
+	InitWatch(db)
+	InitNotification(db)
+	mailer := &fakeMailer{}
+	InitEmail(db, mailer)
+	t.Cleanup(func() { Mailer = nil })
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+	r.POST("/email/verify", VerifyEmail)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/tasks/:id/comments", CreateComment)
+		auth.PUT("/me/notification-preferences", UpdateNotificationPreferences)
+		auth.GET("/me/email-settings", GetEmailSettings)
+		auth.PUT("/me/email-settings", UpdateEmailSettings)
+	}
+	return r, db, mailer
+}
+
+func TestAssignmentAndMentionEmails(t *testing.T) {
+	r, _, mailer := setupEmailTestEnv(t)
+	owner := loginAs(r, t, "owner")
+	helper := loginAs(r, t, "helper")
+
+	if w := doJSON(r, "PUT", "/me/email-settings", helper, `{"email": "not an address"}`); w.Code != 400 {
+		t.Fatalf("Expected 400 for a bad address, got %d", w.Code)
+	}
+	doJSON(r, "PUT", "/me/email-settings", helper, `{"email": "helper@example.com"}`)
+	doJSON(r, "PUT", "/me/email-settings", owner, `{"email": "owner@example.com"}`)
+	mailer.waitFor(t, 2) // verification emails
+	mailer.reset()
+
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Book <van>", "assignee_id": 2}`)
+	sent := mailer.waitFor(t, 1)
+	if sent[0].To != "helper@example.com" || sent[0].Subject != `owner assigned you "Book <van>"` {
+		t.Fatalf("Unexpected assignment email %+v", sent[0])
+	}
+	if !strings.Contains(sent[0].HTML, "Book &lt;van&gt;") {
+		t.Fatalf("Expected escaped HTML, got %q", sent[0].HTML)
+	}
+
+	// Comments aren't emailed by default, mentions are
+	doJSON(r, "POST", "/tasks/1/comments", helper, `{"body": "Done, @owner and @nobody"}`)
+	sent = mailer.waitFor(t, 2)
+	if len(sent) != 2 || sent[1].To != "owner@example.com" || !strings.Contains(sent[1].Text, "Done, @owner") {
+		t.Fatalf("Expected one mention email to the owner, got %+v", sent)
+	}
+
+	// Opting out stops assignment emails
+	doJSON(r, "PUT", "/me/notification-preferences", helper, `[{"event_type": "task.assigned", "email": false}]`)
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Return van", "assignee_id": 2}`)
+	time.Sleep(50 * time.Millisecond)
+	if sent = mailer.waitFor(t, 2); len(sent) != 2 {
+		t.Fatalf("Expected no email after opting out, got %+v", sent[2:])
+	}
+}
+
+func TestDueSoonAndDigest(t *testing.T) {
+	_, db, mailer := setupEmailTestEnv(t)
+	now := time.Date(2025, 5, 10, 9, 0, 0, 0, time.Local)
+
+	user := models.User{Username: "ann", Email: "ann@example.com", DailyDigest: true}
+	db.Create(&user)
+	soon, overdue := now.Add(2*time.Hour), now.Add(-48*time.Hour)
+	db.Create(&models.Task{Title: "Soon", Status: "todo", UserID: user.ID, DueDate: &soon})
+	db.Create(&models.Task{Title: "Late", Status: "todo", UserID: user.ID, DueDate: &overdue})
+
+	publishDueSoon(db, now)
+	publishDueSoon(db, now)
+	sent := mailer.waitFor(t, 1)
+	if len(sent) != 1 || sent[0].Subject != `"Soon" is due soon` {
+		t.Fatalf("Expected a single due-soon email, got %+v", sent)
+	}
+
+	sendDigests(db, now)
+	sendDigests(db, now)
+	time.Sleep(50 * time.Millisecond)
+	sent = mailer.waitFor(t, 2)
+	if len(sent) != 2 {
+		t.Fatalf("Expected a single digest, got %+v", sent)
+	}
+	if !strings.Contains(sent[1].Text, "Overdue:\n- #2 Late") || !strings.Contains(sent[1].Text, "Due today:\n- #1 Soon") {
+		t.Fatalf("Unexpected digest %q", sent[1].Text)
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	r, db, mailer := setupEmailTestEnv(t)
+	ann := loginAs(r, t, "ann")
+
+	w := doJSON(r, "PUT", "/me/email-settings", ann, `{"email": "Ann <ann@example.com>"}`)
+	var settings models.EmailSettings
+	json.Unmarshal(w.Body.Bytes(), &settings)
+	if w.Code != 200 || settings.Email != "ann@example.com" || settings.Verified {
+		t.Fatalf("Expected the bare, unverified address, got %d: %+v", w.Code, settings)
+	}
+	sent := mailer.waitFor(t, 1)
+	if sent[0].To != "ann@example.com" {
+		t.Fatalf("Expected the verification email at the new address, got %+v", sent[0])
+	}
+	token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(sent[0].Text)
+
+	// Saving again while the token is valid doesn't send another one
+	doJSON(r, "PUT", "/me/email-settings", ann, `{"email": "ann@example.com", "daily_digest": true}`)
+	time.Sleep(20 * time.Millisecond)
+	if sent = mailer.waitFor(t, 1); len(sent) != 1 {
+		t.Fatalf("Expected a single verification email, got %d", len(sent))
+	}
+
+	if w := doJSON(r, "POST", "/email/verify", "", `{"token": "nope"}`); w.Code != 400 {
+		t.Fatalf("Expected 400 for a bad token, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/email/verify", "", `{"token": "`+token+`"}`); w.Code != 200 {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(doJSON(r, "GET", "/me/email-settings", ann, "").Body.Bytes(), &settings)
+	if !settings.Verified {
+		t.Fatalf("Expected the address to be verified, got %+v", settings)
+	}
+	if w := doJSON(r, "POST", "/email/verify", "", `{"token": "`+token+`"}`); w.Code != 400 {
+		t.Fatalf("Expected the token to work once, got %d", w.Code)
+	}
+
+	// A new address needs verifying again, and an old token can't vouch for it
+	json.Unmarshal(doJSON(r, "PUT", "/me/email-settings", ann, `{"email": "ann@work.example"}`).Body.Bytes(), &settings)
+	var user models.User
+	db.First(&user)
+	if settings.Verified || user.EmailVerifiedAt != nil {
+		t.Fatalf("Expected the new address to be unverified, got %+v", settings)
+	}
+	mailer.waitFor(t, 2)
+}
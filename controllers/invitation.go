@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"errors"
+	"go_task_api/models"
+	"go_task_api/utils"
+	"log"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// InvitationTTL is how long an invitation stays valid unless the inviter
+// chooses otherwise, and MaxInvitationTTL the longest they may choose
+var (
+	InvitationTTL    = 7 * 24 * time.Hour
+	MaxInvitationTTL = 30 * 24 * time.Hour
+)
+
+var errInvalidInvitation = errors.New("Invalid or expired invitation")
+
+// @Summary Invite someone to sign up
+// @Description Admins can invite anyone, with any role. Project owners can invite regular users into projects they own, which the new user joins as a member. The token in the response is shown only once.
+// @Tags Invitations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param invitation body models.InvitationRequest true "Invitation"
+// @Success 201 {object} models.Invitation
+// @Failure 400,403 {object} map[string]string
+// @Router /invitations [post]
+func CreateInvitation(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	isAdmin := c.GetString("role") == "admin"
+
+	var input models.InvitationRequest
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if input.Role == "" {
+		input.Role = "user"
+	}
+	if input.Role != "user" && input.Role != "admin" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'admin' or 'user'"})
+		return
+	}
+	if input.Role == "admin" && !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can invite admins"})
+		return
+	}
+	if input.Email != "" {
+		if _, err := mail.ParseAddress(input.Email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+			return
+		}
+	}
+
+	projectIDs := uniqueIDs(input.ProjectIDs)
+	if !isAdmin && len(projectIDs) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and project owners can invite people"})
+		return
+	}
+	if len(projectIDs) > 0 {
+		query := DB.Model(&models.Project{}).Where("id IN ?", projectIDs)
+		if !isAdmin {
+			query = query.Where("user_id = ?", userID)
+		}
+		var count int64
+		query.Count(&count)
+		if int(count) != len(projectIDs) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only invite people to projects you own"})
+			return
+		}
+	}
+
+	ttl := InvitationTTL
+	if input.ExpiresIn != 0 {
+		ttl = time.Duration(input.ExpiresIn) * time.Hour
+	}
+	if ttl <= 0 || ttl > MaxInvitationTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_hours must be between 1 and 720"})
+		return
+	}
+
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	invitation := models.Invitation{
+		TokenHash:  hash,
+		Email:      input.Email,
+		Role:       input.Role,
+		ProjectIDs: projectIDs,
+		InvitedBy:  userID,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+	if err := DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	invitation.Token = token
+	invitation.SetStatus(time.Now())
+
+	if Mailer != nil && invitation.Email != "" {
+		data := emailData{Invitation: invitation}
+		DB.First(&data.Actor, userID)
+		if msg, err := renderEmail("invitation", data); err != nil {
+			log.Printf("rendering invitation email: %v", err)
+		} else {
+			msg.To = invitation.Email
+			queueEmail(msg)
+		}
+	}
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// @Summary List invitations
+// @Description Admins see every invitation, everyone else the ones they sent.
+// @Tags Invitations
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Invitation
+// @Router /invitations [get]
+func GetInvitations(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	query := DB.Order("id DESC")
+	if c.GetString("role") != "admin" {
+		query = query.Where("invited_by = ?", userID)
+	}
+	invitations := []models.Invitation{}
+	query.Find(&invitations)
+
+	now := time.Now()
+	for i := range invitations {
+		invitations[i].SetStatus(now)
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// @Summary Revoke an invitation
+// @Tags Invitations
+// @Security BearerAuth
+// @Param id path int true "Invitation ID"
+// @Success 204
+// @Failure 404,409 {object} map[string]string
+// @Router /invitations/{id} [delete]
+func RevokeInvitation(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	query := DB.Where("id = ?", c.Param("id"))
+	if c.GetString("role") != "admin" {
+		query = query.Where("invited_by = ?", userID)
+	}
+	var invitation models.Invitation
+	if err := query.First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if invitation.AcceptedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation was already accepted"})
+		return
+	}
+
+	if invitation.RevokedAt == nil {
+		DB.Model(&invitation).Update("revoked_at", time.Now())
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// claimInvitation marks the invitation with the given token accepted, failing
+// unless it is still pending. Claiming is atomic, so a token works only once.
+func claimInvitation(tx *gorm.DB, token string) (models.Invitation, error) {
+	var invitation models.Invitation
+	if err := tx.Where("token_hash = ?", utils.HashToken(token)).First(&invitation).Error; err != nil {
+		return invitation, errInvalidInvitation
+	}
+
+	now := time.Now()
+	claimed := tx.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", invitation.ID, now).
+		Update("accepted_at", now)
+	if claimed.Error != nil {
+		return invitation, claimed.Error
+	}
+	if claimed.RowsAffected == 0 {
+		return invitation, errInvalidInvitation
+	}
+	invitation.AcceptedAt = &now
+	return invitation, nil
+}
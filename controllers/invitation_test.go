@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/events"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupInvitationTestEnv(t *testing.T) (*gin.Engine, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitProject(db)
+	InitTask(db)
+	InitWatch(db)
+	t.Cleanup(func() { RegistrationMode = RegistrationOpen })
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/projects", CreateProject)
+		auth.GET("/projects", GetProjects)
+		auth.GET("/projects/:id/tasks", GetProjectTasks)
+		auth.GET("/projects/:id/members", GetProjectMembers)
+		auth.DELETE("/projects/:id/members/:user_id", RemoveProjectMember)
+		auth.POST("/tasks", CreateTask)
+		auth.GET("/tasks/:id", GetTask)
+		auth.PUT("/tasks/:id", UpdateTask)
+		auth.GET("/me/watching", GetWatching)
+		auth.POST("/invitations", CreateInvitation)
+		auth.GET("/invitations", GetInvitations)
+		auth.DELETE("/invitations/:id", RevokeInvitation)
+	}
+	return r, db
+}
+
+func TestInvitations(t *testing.T) {
+	r, db := setupInvitationTestEnv(t)
+	admin := loginAs(r, t, "admin")
+	owner := loginAs(r, t, "owner")
+	outsider := loginAs(r, t, "outsider")
+	RegistrationMode = RegistrationInviteOnly
+	doJSON(r, "POST", "/projects", owner, `{"name": "Onboarding"}`)
+	doJSON(r, "POST", "/projects", outsider, `{"name": "Private"}`)
+
+	if w := doJSON(r, "POST", "/register", "", `{"username": "walkin", "password": "secret"}`); w.Code != 403 {
+		t.Fatalf("Expected 403 without an invitation, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/invitations", owner, `{"role": "admin", "project_ids": [1]}`); w.Code != 403 {
+		t.Fatalf("Expected only admins to invite admins, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/invitations", owner, `{"email": "x@example.com"}`); w.Code != 403 {
+		t.Fatalf("Expected non-admins to need a project, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/invitations", owner, `{"project_ids": [1, 2]}`); w.Code != 403 {
+		t.Fatalf("Expected owners to invite into their own projects only, got %d", w.Code)
+	}
+
+	var invitation models.Invitation
+	json.Unmarshal(doJSON(r, "POST", "/invitations", owner, `{"email": "new@example.com", "project_ids": [1, 1]}`).Body.Bytes(), &invitation)
+	if invitation.Token == "" || invitation.Status != "pending" || len(invitation.ProjectIDs) != 1 {
+		t.Fatalf("Unexpected invitation %+v", invitation)
+	}
+
+	// Accepting goes through Register and uses the invitation once
+	body := `{"username": "newbie", "password": "secret", "invite_token": "` + invitation.Token + `"}`
+	if w := doJSON(r, "POST", "/register", "", body); w.Code != 201 {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	body = `{"username": "sneaky", "password": "secret", "invite_token": "` + invitation.Token + `"}`
+	if w := doJSON(r, "POST", "/register", "", body); w.Code != 400 {
+		t.Fatalf("Expected a used invitation to be refused, got %d", w.Code)
+	}
+	var newbie models.User
+	db.Where("username = ?", "newbie").First(&newbie)
+	if newbie.Role != "user" || newbie.Email != "new@example.com" {
+		t.Fatalf("Unexpected user %+v", newbie)
+	}
+	var member models.ProjectMember
+	if err := db.Where("project_id = ? AND user_id = ?", 1, newbie.ID).First(&member).Error; err != nil {
+		t.Fatalf("Expected the invitee to join the project: %v", err)
+	}
+
+	// Admins can invite admins; revoked and expired invitations can't be used
+	var adminInvite, expired models.Invitation
+	json.Unmarshal(doJSON(r, "POST", "/invitations", admin, `{"role": "admin"}`).Body.Bytes(), &adminInvite)
+	json.Unmarshal(doJSON(r, "POST", "/invitations", admin, `{}`).Body.Bytes(), &expired)
+	db.Model(&models.Invitation{}).Where("id = ?", expired.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if w := doJSON(r, "DELETE", "/invitations/2", owner, ""); w.Code != 404 {
+		t.Fatalf("Expected owners not to revoke others' invitations, got %d", w.Code)
+	}
+	doJSON(r, "DELETE", "/invitations/2", admin, "")
+	for _, token := range []string{adminInvite.Token, expired.Token} {
+		body = `{"username": "late", "password": "secret", "invite_token": "` + token + `"}`
+		if w := doJSON(r, "POST", "/register", "", body); w.Code != 400 {
+			t.Fatalf("Expected 400, got %d", w.Code)
+		}
+	}
+
+	var list []models.Invitation
+	json.Unmarshal(doJSON(r, "GET", "/invitations", admin, "").Body.Bytes(), &list)
+	if len(list) != 3 || list[0].Status != "expired" || list[1].Status != "revoked" || list[2].Status != "accepted" || list[0].Token != "" {
+		t.Fatalf("Unexpected invitation list %+v", list)
+	}
+	list = nil
+	json.Unmarshal(doJSON(r, "GET", "/invitations", owner, "").Body.Bytes(), &list)
+	if len(list) != 1 {
+		t.Fatalf("Expected owners to see their own invitations only, got %+v", list)
+	}
+}
+
+func TestProjectMembership(t *testing.T) {
+	r, db := setupInvitationTestEnv(t)
+	admin := loginAs(r, t, "admin")
+	owner := loginAs(r, t, "owner")
+	doJSON(r, "POST", "/projects", owner, `{"name": "Onboarding"}`)
+	doJSON(r, "POST", "/projects", owner, `{"name": "Secret"}`)
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Read the handbook", "project_id": 1}`)
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Plan the party", "project_id": 2}`)
+	// Tasks someone else files under the project don't come with membership
+	doJSON(r, "POST", "/tasks", admin, `{"title": "Admin's own", "project_id": 1}`)
+
+	var invitation models.Invitation
+	json.Unmarshal(doJSON(r, "POST", "/invitations", owner, `{"project_ids": [1]}`).Body.Bytes(), &invitation)
+	doJSON(r, "POST", "/register", "", `{"username": "newbie", "password": "secret", "invite_token": "`+invitation.Token+`"}`)
+	var tokens models.TokenResponse
+	json.Unmarshal(doJSON(r, "POST", "/login", "", `{"username": "newbie", "password": "secret"}`).Body.Bytes(), &tokens)
+	newbie := tokens.Token
+
+	var projects []models.Project
+	json.Unmarshal(doJSON(r, "GET", "/projects", newbie, "").Body.Bytes(), &projects)
+	if len(projects) != 1 || projects[0].Name != "Onboarding" {
+		t.Fatalf("Expected to see the invited project only, got %+v", projects)
+	}
+	var tasks []models.Task
+	json.Unmarshal(doJSON(r, "GET", "/projects/1/tasks", newbie, "").Body.Bytes(), &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "Read the handbook" {
+		t.Fatalf("Expected the project's tasks, got %+v", tasks)
+	}
+	if w := doJSON(r, "GET", "/tasks/1", newbie, ""); w.Code != 200 {
+		t.Fatalf("Expected members to open project tasks, got %d", w.Code)
+	}
+	for _, path := range []string{"/tasks/2", "/tasks/3", "/projects/2/tasks"} {
+		if w := doJSON(r, "GET", path, newbie, ""); w.Code != 404 {
+			t.Fatalf("Expected 404 for %s, got %d", path, w.Code)
+		}
+	}
+	if w := doJSON(r, "PUT", "/tasks/1", newbie, `{"title": "Skip it"}`); w.Code != 404 {
+		t.Fatalf("Expected members not to edit, got %d", w.Code)
+	}
+	var watching models.Watching
+	json.Unmarshal(doJSON(r, "GET", "/me/watching", newbie, "").Body.Bytes(), &watching)
+	if len(watching.Projects) != 1 || watching.Projects[0].ID != 1 {
+		t.Fatalf("Expected the invited project to be followed, got %+v", watching)
+	}
+
+	var members []models.ProjectMember
+	json.Unmarshal(doJSON(r, "GET", "/projects/1/members", owner, "").Body.Bytes(), &members)
+	if len(members) != 1 || members[0].Username != "newbie" {
+		t.Fatalf("Unexpected members %+v", members)
+	}
+	memberID := members[0].UserID
+	if !canSeeEvent(db, memberID, events.Event{TaskID: 1}) || canSeeEvent(db, memberID, events.Event{TaskID: 3}) {
+		t.Fatal("Expected members to get events for the project's tasks only")
+	}
+	if !canSeeProject(db, memberID, 1) || canSeeProject(db, memberID, 2) {
+		t.Fatal("Expected members to subscribe to the project only")
+	}
+	path := "/projects/1/members/" + strconv.Itoa(int(members[0].UserID))
+	if w := doJSON(r, "DELETE", "/projects/1/members/1", newbie, ""); w.Code != 403 {
+		t.Fatalf("Expected members not to remove others, got %d", w.Code)
+	}
+	if w := doJSON(r, "DELETE", path, owner, ""); w.Code != 204 {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	if w := doJSON(r, "GET", "/tasks/1", newbie, ""); w.Code != 404 {
+		t.Fatalf("Expected removed members to lose access, got %d", w.Code)
+	}
+	var watches int64
+	db.Model(&models.Watch{}).Where("user_id = ?", members[0].UserID).Count(&watches)
+	if watches != 0 {
+		t.Fatalf("Expected removed members to stop following the project, got %d watches", watches)
+	}
+	if w := doJSON(r, "DELETE", path, owner, ""); w.Code != 404 {
+		t.Fatalf("Expected 404 for a non-member, got %d", w.Code)
+	}
+}
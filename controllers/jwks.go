@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"go_task_api/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Public keys for verifying access tokens
+// @Description JSON Web Key Set with every RS256 and EdDSA key currently accepted. Empty when tokens are signed with HS256.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"errors"
+	"go_task_api/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var MilestoneDB *gorm.DB
+
+func InitMilestone(db *gorm.DB) {
+	MilestoneDB = db
+}
+
+// @Summary Create a milestone in a project
+// @Tags Milestones
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param milestone body models.Milestone true "Milestone name and dates"
+// @Success 201 {object} models.Milestone
+// @Failure 400,404 {object} map[string]string
+// @Router /projects/{id}/milestones [post]
+func CreateMilestone(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := MilestoneDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var milestone models.Milestone
+	if err := c.BindJSON(&milestone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	milestone.ID = 0
+	milestone.ProjectID = project.ID
+	milestone.ClosedAt = nil
+	milestone.Name = strings.TrimSpace(milestone.Name)
+
+	if milestone.Name == "" || milestone.StartDate.IsZero() || milestone.EndDate.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name, start_date and end_date are required"})
+		return
+	}
+	if milestone.EndDate.Before(milestone.StartDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+
+	MilestoneDB.Create(&milestone)
+	c.JSON(http.StatusCreated, milestone)
+}
+
+// @Summary List a project's milestones
+// @Tags Milestones
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} models.Milestone
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/milestones [get]
+func GetMilestones(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := visibleProjects(MilestoneDB, userID).Where("id = ?", c.Param("id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var milestones []models.Milestone
+	MilestoneDB.Where("project_id = ?", project.ID).Order("start_date, id").Find(&milestones)
+	c.JSON(http.StatusOK, milestones)
+}
+
+// @Summary Get a milestone with progress, scope changes and unfinished tasks
+// @Tags Milestones
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Milestone ID"
+// @Success 200 {object} models.MilestoneReport
+// @Failure 404 {object} map[string]string
+// @Router /milestones/{id} [get]
+func GetMilestone(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	milestone, err := findUserMilestone(MilestoneDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+		return
+	}
+
+	var tasks []models.Task
+	MilestoneDB.Where("milestone_id = ?", milestone.ID).Order("position, id").Find(&tasks)
+
+	report := models.MilestoneReport{
+		Milestone:       milestone,
+		Total:           len(tasks),
+		ScopeChanges:    []models.ScopeChangeDay{},
+		UnfinishedTasks: []models.Task{},
+	}
+	for _, task := range tasks {
+		if task.IsClosed() {
+			report.Done++
+		} else {
+			report.UnfinishedTasks = append(report.UnfinishedTasks, task)
+		}
+	}
+	if report.Total > 0 {
+		report.Percent = float64(report.Done) * 100 / float64(report.Total)
+	}
+
+	var changes []models.MilestoneScopeChange
+	MilestoneDB.Where("milestone_id = ?", milestone.ID).Order("created_at, id").Find(&changes)
+	scope := 0
+	for _, change := range changes {
+		date := change.CreatedAt.UTC().Format("2006-01-02")
+		if n := len(report.ScopeChanges); n == 0 || report.ScopeChanges[n-1].Date != date {
+			report.ScopeChanges = append(report.ScopeChanges, models.ScopeChangeDay{Date: date})
+		}
+		day := &report.ScopeChanges[len(report.ScopeChanges)-1]
+		if change.Delta > 0 {
+			day.Added += change.Delta
+		} else {
+			day.Removed -= change.Delta
+		}
+		scope += change.Delta
+		day.Scope = scope
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Close a milestone
+// @Description Unfinished tasks roll over to next_milestone_id, or to the next open milestone of the project by start date.
+// @Tags Milestones
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Milestone ID"
+// @Param input body models.CloseMilestoneRequest false "Milestone to roll unfinished tasks into"
+// @Success 200 {object} models.Milestone
+// @Failure 400,404,409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /milestones/{id}/close [post]
+func CloseMilestone(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	milestone, err := findUserMilestone(MilestoneDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+		return
+	}
+	if milestone.ClosedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Milestone is already closed"})
+		return
+	}
+
+	var input models.CloseMilestoneRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	var next models.Milestone
+	query := MilestoneDB.Where("project_id = ? AND id <> ? AND closed_at IS NULL", milestone.ProjectID, milestone.ID)
+	if input.NextMilestoneID != nil {
+		err = query.Where("id = ?", *input.NextMilestoneID).First(&next).Error
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Next milestone must be an open milestone of the same project"})
+			return
+		}
+	} else {
+		err = query.Where("start_date >= ?", milestone.StartDate).Order("start_date, id").First(&next).Error
+	}
+	hasNext := err == nil
+
+	var unfinished []models.Task
+	MilestoneDB.Where("milestone_id = ? AND status NOT IN ?", milestone.ID, models.ClosedStatuses).Find(&unfinished)
+	if len(unfinished) > 0 && !hasNext {
+		c.JSON(http.StatusConflict, gin.H{"error": "No open milestone to roll unfinished tasks into, create the next one first"})
+		return
+	}
+
+	now := MilestoneDB.NowFunc()
+	err = MilestoneDB.Transaction(func(tx *gorm.DB) error {
+		for _, task := range unfinished {
+			if err := tx.Model(&task).Update("milestone_id", next.ID).Error; err != nil {
+				return err
+			}
+			if err := recordMilestoneChange(tx, task.ID, &milestone.ID, &next.ID); err != nil {
+				return err
+			}
+		}
+		milestone.ClosedAt = &now
+		return tx.Model(&milestone).Update("closed_at", now).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close milestone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, milestone)
+}
+
+// findUserMilestone loads a milestone of one of the user's projects.
+func findUserMilestone(db *gorm.DB, id string, userID uint) (models.Milestone, error) {
+	var milestone models.Milestone
+	err := db.Joins("JOIN projects ON projects.id = milestones.project_id").
+		Where("milestones.id = ? AND projects.user_id = ?", id, userID).
+		First(&milestone).Error
+	return milestone, err
+}
+
+// checkMilestone makes sure a task can be assigned to the milestone.
+func checkMilestone(db *gorm.DB, milestoneID *uint, projectID uint) error {
+	if milestoneID == nil {
+		return nil
+	}
+	var milestone models.Milestone
+	if err := db.Where("id = ? AND project_id = ?", *milestoneID, projectID).First(&milestone).Error; err != nil {
+		return errors.New("milestone must belong to the task's project")
+	}
+	if milestone.ClosedAt != nil {
+		return errors.New("milestone is closed")
+	}
+	return nil
+}
+
+// recordMilestoneChange logs a task moving between milestones for the scope history.
+func recordMilestoneChange(tx *gorm.DB, taskID uint, from, to *uint) error {
+	if from != nil && to != nil && *from == *to {
+		return nil
+	}
+	if from != nil {
+		if err := tx.Create(&models.MilestoneScopeChange{MilestoneID: *from, TaskID: taskID, Delta: -1}).Error; err != nil {
+			return err
+		}
+	}
+	if to != nil {
+		if err := tx.Create(&models.MilestoneScopeChange{MilestoneID: *to, TaskID: taskID, Delta: 1}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
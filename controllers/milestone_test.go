@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupMilestoneTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitMilestone(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.PUT("/tasks/:id", UpdateTask)
+		auth.DELETE("/tasks/:id", DeleteTask)
+		auth.POST("/projects", CreateProject)
+		auth.POST("/projects/:id/milestones", CreateMilestone)
+		auth.GET("/milestones/:id", GetMilestone)
+		auth.POST("/milestones/:id/close", CloseMilestone)
+	}
+	return r
+}
+
+func TestMilestoneProgressAndClose(t *testing.T) {
+	r := setupMilestoneTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Product"}`)
+	if w := doJSON(r, "POST", "/projects/1/milestones", token, `{"name": "Sprint 1", "start_date": "2025-05-05T00:00:00Z", "end_date": "2025-05-16T00:00:00Z"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d: %s", w.Code, w.Body.String())
+	}
+	doJSON(r, "POST", "/tasks", token, `{"title": "a", "status": "done", "project_id": 1, "milestone_id": 1}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "b", "status": "todo", "project_id": 1, "milestone_id": 1}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "c", "status": "todo", "project_id": 1, "milestone_id": 1}`)
+	doJSON(r, "PUT", "/tasks/3", token, `{"milestone_id": null}`)
+
+	w := doJSON(r, "GET", "/milestones/1", token, "")
+	var report models.MilestoneReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if report.Done != 1 || report.Total != 2 || len(report.UnfinishedTasks) != 1 {
+		t.Fatalf("Unexpected progress: %+v", report)
+	}
+	if len(report.ScopeChanges) != 1 || report.ScopeChanges[0].Added != 3 || report.ScopeChanges[0].Removed != 1 || report.ScopeChanges[0].Scope != 2 {
+		t.Fatalf("Unexpected scope changes: %+v", report.ScopeChanges)
+	}
+
+	if w := doJSON(r, "POST", "/milestones/1/close", token, ""); w.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 without a next milestone, got %d", w.Code)
+	}
+
+	doJSON(r, "POST", "/projects/1/milestones", token, `{"name": "Sprint 2", "start_date": "2025-05-19T00:00:00Z", "end_date": "2025-05-30T00:00:00Z"}`)
+	if w := doJSON(r, "POST", "/milestones/1/close", token, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(r, "GET", "/milestones/2", token, "")
+	report = models.MilestoneReport{}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if report.Total != 1 || report.UnfinishedTasks[0].Title != "b" {
+		t.Fatalf("Expected unfinished task to roll over, got %+v", report)
+	}
+}
+
+func TestDeletingTaskShrinksMilestoneScope(t *testing.T) {
+	r := setupMilestoneTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Product"}`)
+	doJSON(r, "POST", "/projects/1/milestones", token, `{"name": "Sprint 1", "start_date": "2025-05-05T00:00:00Z", "end_date": "2025-05-16T00:00:00Z"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "a", "project_id": 1, "milestone_id": 1}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "b", "project_id": 1, "milestone_id": 1}`)
+	if w := doJSON(r, "DELETE", "/tasks/2", token, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+
+	w := doJSON(r, "GET", "/milestones/1", token, "")
+	var report models.MilestoneReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if report.Total != 1 || len(report.ScopeChanges) != 1 || report.ScopeChanges[0].Removed != 1 || report.ScopeChanges[0].Scope != 1 {
+		t.Fatalf("Expected the deleted task to leave the scope, got %+v", report)
+	}
+}
+
+func TestMovingTaskToAnotherProjectChecksMilestone(t *testing.T) {
+	r := setupMilestoneTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Product"}`)
+	doJSON(r, "POST", "/projects", token, `{"name": "Marketing"}`)
+	doJSON(r, "POST", "/projects/1/milestones", token, `{"name": "Sprint 1", "start_date": "2025-05-05T00:00:00Z", "end_date": "2025-05-16T00:00:00Z"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "a", "project_id": 1, "milestone_id": 1}`)
+
+	if w := doJSON(r, "PUT", "/tasks/1", token, `{"project_id": 2}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a milestone from the old project, got %d", w.Code)
+	}
+	if w := doJSON(r, "PUT", "/tasks/1", token, `{"project_id": 2, "milestone_id": null}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 when the milestone is cleared, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"fmt"
+	"go_task_api/events"
+	"go_task_api/models"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var NotificationDB *gorm.DB
+
+// NotificationRetention is how long notifications are kept before being pruned
+var NotificationRetention = 90 * 24 * time.Hour
+
+var subscribeNotifications sync.Once
+
+func InitNotification(db *gorm.DB) {
+	NotificationDB = db
+	subscribeNotifications.Do(func() {
+		events.Subscribe(notifyRecipients)
+	})
+}
+
+// @Summary List your notifications, newest first
+// @Tags Notifications
+// @Security BearerAuth
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Max number of results (default 20)"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {object} models.NotificationList
+// @Router /notifications [get]
+func GetNotifications(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	limit := toInt(c.DefaultQuery("limit", "20"))
+	offset := toInt(c.DefaultQuery("offset", "0"))
+
+	query := NotificationDB.Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	list := models.NotificationList{Notifications: []models.Notification{}}
+	query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&list.Notifications)
+	NotificationDB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&list.Unread)
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Mark a notification as read
+// @Tags Notifications
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 404 {object} map[string]string
+// @Router /notifications/{id}/read [post]
+func MarkNotificationRead(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var notification models.Notification
+	if err := NotificationDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := NotificationDB.NowFunc()
+		notification.ReadAt = &now
+		NotificationDB.Model(&notification).Update("read_at", now)
+	}
+	c.JSON(http.StatusOK, notification)
+}
+
+// @Summary Mark all your notifications as read
+// @Tags Notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]int64
+// @Router /notifications/read-all [post]
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	result := NotificationDB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", NotificationDB.NowFunc())
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
+
+// @Summary Get your notification preferences for every event type
+// @Tags Notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.NotificationPreference
+// @Router /me/notification-preferences [get]
+func GetNotificationPreferences(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	c.JSON(http.StatusOK, notificationPreferences(NotificationDB, userID))
+}
+
+// @Summary Update your notification preferences
+// @Description Only the event types sent are changed.
+// @Tags Notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param preferences body []models.NotificationPreferenceRequest true "Preferences by event type"
+// @Success 200 {array} models.NotificationPreference
+// @Failure 400 {object} map[string]string
+// @Router /me/notification-preferences [put]
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input []models.NotificationPreferenceRequest
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	for _, pref := range input {
+		if !isEventType(pref.EventType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown event type %q", pref.EventType)})
+			return
+		}
+	}
+
+	current := make(map[string]models.NotificationPreference)
+	for _, pref := range notificationPreferences(NotificationDB, userID) {
+		current[pref.EventType] = pref
+	}
+
+	err := NotificationDB.Transaction(func(tx *gorm.DB) error {
+		for _, change := range input {
+			pref := current[change.EventType]
+			pref.ID = 0
+			pref.UserID = userID
+			if change.InApp != nil {
+				pref.InApp = *change.InApp
+			}
+			if change.Email != nil {
+				pref.Email = *change.Email
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"in_app", "email"}),
+			}).Create(&pref).Error; err != nil {
+				return err
+			}
+			current[change.EventType] = pref
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notificationPreferences(NotificationDB, userID))
+}
+
+// StartNotificationPruning deletes notifications older than NotificationRetention
+// every interval, until the process exits.
+func StartNotificationPruning(interval time.Duration) {
+	go func() {
+		for {
+			pruneNotifications(NotificationDB, time.Now().Add(-NotificationRetention))
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func pruneNotifications(db *gorm.DB, before time.Time) int64 {
+	result := db.Where("created_at < ?", before).Delete(&models.Notification{})
+	if result.Error != nil {
+		log.Printf("pruning notifications: %v", result.Error)
+	}
+	return result.RowsAffected
+}
+
+// notificationPreferences returns the user's preference for every event type,
+// filling in the defaults for types they haven't changed.
+func notificationPreferences(db *gorm.DB, userID uint) []models.NotificationPreference {
+	var stored []models.NotificationPreference
+	db.Where("user_id = ?", userID).Find(&stored)
+	byType := make(map[string]models.NotificationPreference, len(stored))
+	for _, pref := range stored {
+		byType[pref.EventType] = pref
+	}
+
+	prefs := make([]models.NotificationPreference, len(events.Types))
+	for i, eventType := range events.Types {
+		pref, ok := byType[eventType]
+		if !ok {
+			pref = models.NotificationPreference{
+				UserID:    userID,
+				EventType: eventType,
+				InApp:     true,
+				Email:     emailByDefault[eventType],
+			}
+		}
+		prefs[i] = pref
+	}
+	return prefs
+}
+
+func isEventType(eventType string) bool {
+	for _, t := range events.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// eventRecipients returns the users who should hear about an event: the task's
+// owner, assignee and watchers, minus whoever made the change.
+func eventRecipients(db *gorm.DB, e events.Event) []uint {
+	var ids []uint
+	switch {
+	case len(e.Recipients) > 0:
+		ids = append(ids, e.Recipients...)
+	case e.Type == events.TaskAssigned:
+		if task, ok := e.Data.(models.Task); ok && task.AssigneeID != nil {
+			ids = []uint{*task.AssigneeID}
+		}
+	case e.TaskID == 0:
+		// Project events have no audience besides the owner, who made them
+		return nil
+	default:
+		var task models.Task
+		if t, ok := e.Data.(models.Task); ok {
+			task = t
+		} else if err := db.First(&task, e.TaskID).Error; err != nil {
+			return nil
+		}
+		ids = append(taskWatcherIDs(db, task), task.UserID)
+		if task.AssigneeID != nil {
+			if e.Type == events.TaskCreated {
+				// The assignee hears about a new task through task.assigned
+				ids = removeID(ids, *task.AssigneeID)
+			} else {
+				ids = append(ids, *task.AssigneeID)
+			}
+		}
+	}
+
+	seen := map[uint]bool{e.ActorID: true}
+	recipients := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			recipients = append(recipients, id)
+		}
+	}
+	return recipients
+}
+
+func removeID(ids []uint, id uint) []uint {
+	out := ids[:0]
+	for _, other := range ids {
+		if other != id {
+			out = append(out, other)
+		}
+	}
+	return out
+}
+
+// notifyRecipients adds an inbox entry for everyone interested in the event
+// who hasn't turned this event type off.
+func notifyRecipients(e events.Event) {
+	// Reminders are stored by fireReminders, along with marking them fired
+	if e.Type == events.TaskReminder {
+		return
+	}
+	if err := storeNotifications(NotificationDB, e); err != nil {
+		log.Printf("storing %s notifications: %v", e.Type, err)
+	}
+}
+
+func storeNotifications(db *gorm.DB, e events.Event) error {
+	recipients := eventRecipients(db, e)
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	var muted []uint
+	db.Model(&models.NotificationPreference{}).
+		Where("user_id IN ? AND event_type = ? AND in_app = ?", recipients, e.Type, false).
+		Pluck("user_id", &muted)
+	skip := make(map[uint]bool, len(muted))
+	for _, id := range muted {
+		skip[id] = true
+	}
+
+	message := notificationMessage(db, e)
+	for _, userID := range recipients {
+		if skip[userID] {
+			continue
+		}
+		if err := db.Create(&models.Notification{
+			UserID:    userID,
+			Type:      e.Type,
+			ActorID:   e.ActorID,
+			ProjectID: e.ProjectID,
+			TaskID:    e.TaskID,
+			Message:   message,
+			CreatedAt: e.CreatedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notificationMessage(db *gorm.DB, e events.Event) string {
+	var actor models.User
+	name := "Someone"
+	if db.Select("username").First(&actor, e.ActorID).Error == nil {
+		name = actor.Username
+	}
+
+	var title string
+	switch data := e.Data.(type) {
+	case models.Task:
+		title = data.Title
+	case models.Project:
+		title = data.Name
+	case models.Comment:
+		var task models.Task
+		db.Select("title").First(&task, data.TaskID)
+		title = task.Title
+	}
+
+	switch e.Type {
+	case events.TaskCreated:
+		return fmt.Sprintf("%s created %q", name, title)
+	case events.TaskUpdated:
+		return fmt.Sprintf("%s updated %q", name, title)
+	case events.TaskDeleted:
+		return fmt.Sprintf("%s deleted %q", name, title)
+	case events.TaskAssigned:
+		return fmt.Sprintf("%s assigned %q to you", name, title)
+	case events.TaskDueSoon:
+		return fmt.Sprintf("%q is due soon", title)
+	case events.TaskReminder:
+		return fmt.Sprintf("Reminder: %q", title)
+	case events.CommentCreated:
+		return fmt.Sprintf("%s commented on %q", name, title)
+	case events.CommentMentioned:
+		return fmt.Sprintf("%s mentioned you on %q", name, title)
+	case events.ProjectCreated:
+		return fmt.Sprintf("%s created project %q", name, title)
+	}
+	return fmt.Sprintf("%s: %s", e.Type, title)
+}
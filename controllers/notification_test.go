@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupNotificationTestEnv() (*gin.Engine, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitComment(db)
+	InitWatch(db)
+	InitNotification(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.PUT("/tasks/:id", UpdateTask)
+		auth.DELETE("/tasks/:id", DeleteTask)
+		auth.POST("/tasks/:id/comments", CreateComment)
+		auth.GET("/notifications", GetNotifications)
+		auth.POST("/notifications/read-all", MarkAllNotificationsRead)
+		auth.PUT("/me/notification-preferences", UpdateNotificationPreferences)
+	}
+	return r, db
+}
+
+func TestNotificationInbox(t *testing.T) {
+	r, db := setupNotificationTestEnv()
+	owner := loginAs(r, t, "owner")
+	helper := loginAs(r, t, "helper")
+
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Book van", "assignee_id": 2}`)
+	doJSON(r, "POST", "/tasks/1/comments", helper, `{"body": "Booked"}`)
+
+	var list models.NotificationList
+	json.Unmarshal(doJSON(r, "GET", "/notifications", helper, "").Body.Bytes(), &list)
+	if len(list.Notifications) != 1 || list.Notifications[0].Type != "task.assigned" {
+		t.Fatalf("Expected an assignment notification, got %+v", list)
+	}
+
+	list = models.NotificationList{}
+	json.Unmarshal(doJSON(r, "GET", "/notifications?unread=true", owner, "").Body.Bytes(), &list)
+	if list.Unread != 1 || list.Notifications[0].Message != `helper commented on "Book van"` {
+		t.Fatalf("Expected a comment notification, got %+v", list)
+	}
+
+	doJSON(r, "POST", "/notifications/read-all", owner, "")
+	doJSON(r, "PUT", "/me/notification-preferences", owner, `[{"event_type": "comment.created", "in_app": false}]`)
+	doJSON(r, "POST", "/tasks/1/comments", helper, `{"body": "Also got boxes"}`)
+
+	list = models.NotificationList{}
+	json.Unmarshal(doJSON(r, "GET", "/notifications?unread=true", owner, "").Body.Bytes(), &list)
+	if list.Unread != 0 {
+		t.Fatalf("Expected muted comments to be skipped, got %+v", list)
+	}
+
+	if n := pruneNotifications(db, time.Now().Add(time.Minute)); n != 2 {
+		t.Fatalf("Expected 2 notifications to be pruned, got %d", n)
+	}
+}
+
+func TestWatchersHearAboutDeletedTasks(t *testing.T) {
+	r, db := setupNotificationTestEnv()
+	owner := loginAs(r, t, "owner")
+	fan := loginAs(r, t, "fan")
+
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Book van"}`)
+	watchTask(db, 2, 1)
+	if w := doJSON(r, "DELETE", "/tasks/1", owner, ""); w.Code != 204 {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+
+	var list models.NotificationList
+	json.Unmarshal(doJSON(r, "GET", "/notifications", fan, "").Body.Bytes(), &list)
+	if len(list.Notifications) != 1 || list.Notifications[0].Type != "task.deleted" {
+		t.Fatalf("Expected a deletion notification, got %+v", list)
+	}
+	var watches int64
+	db.Model(&models.Watch{}).Where("task_id = ?", 1).Count(&watches)
+	if watches != 0 {
+		t.Fatalf("Expected watches to be removed with the task, got %d", watches)
+	}
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"errors"
+	"go_task_api/models"
+	"go_task_api/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTTL is how long a password reset token stays valid, and
+// PasswordResetInterval how often an account may be sent a new one
+var (
+	PasswordResetTTL      = time.Hour
+	PasswordResetInterval = 5 * time.Minute
+)
+
+var (
+	errInvalidReset   = errors.New("Invalid or expired reset token")
+	errResetThrottled = errors.New("password reset requested too recently")
+)
+
+// @Summary Request a password reset email
+// @Description The answer is the same whether or not the account exists. An email address only finds an account if that account verified it and no other did. The emailed token is valid for an hour; an account gets at most one every five minutes, and earlier tokens keep working until one is used.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body models.ForgotPasswordRequest true "Username or email address"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.BindJSON(&req); err != nil || (req.Username == "" && req.Email == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username or email is required"})
+		return
+	}
+
+	var users []models.User
+	if req.Username != "" {
+		DB.Where("username = ? AND email <> ''", req.Username).Limit(1).Find(&users)
+	} else {
+		// Anyone can type in any address, so only a verified one that names a
+		// single account is trusted
+		DB.Where("email = ? AND email_verified_at IS NOT NULL", req.Email).Limit(2).Find(&users)
+	}
+	if len(users) == 1 && Mailer != nil {
+		user := users[0]
+		if err := sendPasswordReset(DB, user); err != nil {
+			log.Printf("password reset for user %d: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists and has an email address, a reset link is on its way"})
+}
+
+// @Summary Set a new password with a reset token
+// @Description Logs the account out of every session.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.BindJSON(&req); err != nil || req.Token == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and password are required"})
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var reset models.PasswordReset
+		if err := tx.Where("token_hash = ?", utils.HashToken(req.Token)).First(&reset).Error; err != nil {
+			return errInvalidReset
+		}
+		now := time.Now()
+		claimed := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", reset.ID, now).
+			Update("used_at", now)
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			return errInvalidReset
+		}
+		// The account's other outstanding tokens go with it
+		if err := tx.Model(&models.PasswordReset{}).Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, reset.UserID).Error; err != nil {
+			return errInvalidReset
+		}
+		if err := user.SetPassword(req.Password); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("password", user.Password).Error; err != nil {
+			return err
+		}
+		return revokeAllSessions(tx, user.ID)
+	})
+	if errors.Is(err, errInvalidReset) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated, please log in again"})
+}
+
+// sendPasswordReset emails the user a new reset token, unless they were sent
+// one within PasswordResetInterval. Requests from someone else can neither
+// flood their inbox nor cancel a token they already have.
+func sendPasswordReset(db *gorm.DB, user models.User) error {
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(PasswordResetTTL),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var recent int64
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-PasswordResetInterval)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return errResetThrottled
+		}
+		return tx.Create(&reset).Error
+	})
+	if errors.Is(err, errResetThrottled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	msg, err := renderEmail("password_reset", emailData{User: user, ResetToken: token, ResetExpiresAt: reset.ExpiresAt})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	queueEmail(msg)
+	return nil
+}
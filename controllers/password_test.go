@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordReset(t *testing.T) {
+	db := setupTestDB()
+	mailer := &fakeMailer{}
+	InitEmail(db, mailer)
+	middlewares.InitRevocation(db)
+	t.Cleanup(func() {
+		Mailer = nil
+		middlewares.InitRevocation(nil)
+	})
+
+	r := setupRouter()
+	r.POST("/password/forgot", ForgotPassword)
+	r.POST("/password/reset", ResetPassword)
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	auth.GET("/me/sessions", GetSessions)
+
+	doJSON(r, "POST", "/register", "", `{"username": "ann", "password": "old", "email": "ann@example.com"}`)
+	mailer.waitFor(t, 1) // verification email
+	mailer.reset()
+	var session models.TokenResponse
+	json.Unmarshal(doJSON(r, "POST", "/login", "", `{"username": "ann", "password": "old"}`).Body.Bytes(), &session)
+
+	// Unknown accounts get the same answer and no email, and so does an
+	// address nobody verified
+	if w := doJSON(r, "POST", "/password/forgot", "", `{"email": "nobody@example.com"}`); w.Code != 202 {
+		t.Fatalf("Expected 202, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/password/forgot", "", `{"email": "ann@example.com"}`); w.Code != 202 {
+		t.Fatalf("Expected 202, got %d", w.Code)
+	}
+	doJSON(r, "POST", "/password/forgot", "", `{"username": "ann"}`)
+	if sent := mailer.waitFor(t, 1); len(sent) != 1 || sent[0].To != "ann@example.com" {
+		t.Fatalf("Expected one reset email to ann, got %+v", sent)
+	}
+
+	// Asking again straight away sends nothing
+	doJSON(r, "POST", "/password/forgot", "", `{"username": "ann"}`)
+	time.Sleep(20 * time.Millisecond)
+	if sent := mailer.waitFor(t, 1); len(sent) != 1 {
+		t.Fatalf("Expected no second email within the interval, got %d", len(sent))
+	}
+
+	// Once verified, the address finds the account
+	db.Model(&models.User{}).Where("username = ?", "ann").Update("email_verified_at", time.Now())
+	db.Model(&models.PasswordReset{}).Where("user_id = ?", 1).Update("created_at", time.Now().Add(-PasswordResetInterval))
+	doJSON(r, "POST", "/password/forgot", "", `{"email": "ann@example.com"}`)
+	sent := mailer.waitFor(t, 2)
+	tokenPattern := regexp.MustCompile(`[0-9a-f]{64}`)
+	first, latest := tokenPattern.FindString(sent[0].Text), tokenPattern.FindString(sent[1].Text)
+
+	var stored models.PasswordReset
+	db.Last(&stored)
+	if stored.TokenHash == latest {
+		t.Fatalf("Reset tokens must be stored hashed")
+	}
+
+	// A newer request doesn't cancel the older token, using either retires both
+	if w := doJSON(r, "POST", "/password/reset", "", `{"token": "`+latest+`", "password": "new"}`); w.Code != 200 {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(r, "POST", "/password/reset", "", `{"token": "`+first+`", "password": "again"}`); w.Code != 400 {
+		t.Fatalf("Expected the other token to be retired, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/password/reset", "", `{"token": "`+latest+`", "password": "again"}`); w.Code != 400 {
+		t.Fatalf("Expected the token to work once, got %d", w.Code)
+	}
+
+	if w := doJSON(r, "GET", "/me/sessions", session.Token, ""); w.Code != 401 {
+		t.Fatalf("Expected existing sessions to be revoked, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/login", "", `{"username": "ann", "password": "old"}`); w.Code != 401 {
+		t.Fatalf("Expected the old password to stop working, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/login", "", `{"username": "ann", "password": "new"}`); w.Code != 200 {
+		t.Fatalf("Expected the new password to work, got %d", w.Code)
+	}
+}
+
+func TestForgotPasswordWithoutMailer(t *testing.T) {
+	db := setupTestDB()
+	InitEmail(db, nil)
+
+	r := setupRouter()
+	r.POST("/password/forgot", ForgotPassword)
+	doJSON(r, "POST", "/register", "", `{"username": "ann", "password": "old", "email": "ann@example.com"}`)
+
+	// Same answer as with email on, but no token is issued that nobody could receive
+	if w := doJSON(r, "POST", "/password/forgot", "", `{"username": "ann"}`); w.Code != 202 {
+		t.Fatalf("Expected 202, got %d", w.Code)
+	}
+	var count int64
+	db.Model(&models.PasswordReset{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("Expected no reset token without a mailer, got %d", count)
+	}
+}
+
+func TestForgotPasswordSharedEmail(t *testing.T) {
+	db := setupTestDB()
+	mailer := &fakeMailer{}
+	InitEmail(db, mailer)
+	t.Cleanup(func() { Mailer = nil })
+
+	r := setupRouter()
+	r.POST("/password/forgot", ForgotPassword)
+	now := time.Now()
+	db.Create(&models.User{Username: "ann", Email: "team@example.com", EmailVerifiedAt: &now})
+	db.Create(&models.User{Username: "bob", Email: "team@example.com", EmailVerifiedAt: &now})
+
+	// The address doesn't say which account is meant, the username does
+	doJSON(r, "POST", "/password/forgot", "", `{"email": "team@example.com"}`)
+	time.Sleep(20 * time.Millisecond)
+	if sent := mailer.waitFor(t, 0); len(sent) != 0 {
+		t.Fatalf("Expected no email for a shared address, got %+v", sent)
+	}
+	doJSON(r, "POST", "/password/forgot", "", `{"username": "bob"}`)
+	if sent := mailer.waitFor(t, 1); !strings.Contains(sent[0].Text, "Hi bob") {
+		t.Fatalf("Expected bob's reset email, got %+v", sent[0])
+	}
+}
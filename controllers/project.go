@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"go_task_api/events"
 	"go_task_api/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var ProjectDB *gorm.DB
@@ -31,10 +34,13 @@ func CreateProject(c *gin.Context) {
 	}
 	project.UserID = userID
 	ProjectDB.Create(&project)
+	watchProject(ProjectDB, userID, project.ID)
+	publishProjectEvent(userID, project)
 	c.JSON(http.StatusCreated, project)
 }
 
 // @Summary Get all projects
+// @Description Projects the user owns or is a member of.
 // @Tags Projects
 // @Security BearerAuth
 // @Produce json
@@ -43,7 +49,7 @@ func CreateProject(c *gin.Context) {
 func GetProjects(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
 	var projects []models.Project
-	ProjectDB.Where("user_id = ?", userID).Find(&projects)
+	visibleProjects(ProjectDB, userID).Find(&projects)
 	c.JSON(http.StatusOK, projects)
 }
 
@@ -52,7 +58,10 @@ func GetProjects(c *gin.Context) {
 // @Security BearerAuth
 // @Produce json
 // @Param id path int true "Project ID"
+// @Param render query string false "Set to html to include sanitized description_html"
+// @Param group_by query string false "Set to section to group the tasks by project section"
 // @Success 200 {array} models.Task
+// @Success 200 {array} models.SectionGroup
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /projects/{id}/tasks [get]
@@ -60,15 +69,189 @@ func GetProjectTasks(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
 	projectID := c.Param("id")
 
-	// Optional: Check if project exists and belongs to user
+	// Optional: Check if project exists and the user owns it or is a member
 	var project models.Project
-	if err := ProjectDB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
+	if err := visibleProjects(ProjectDB, userID).Where("id = ?", projectID).First(&project).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
 
 	var tasks []models.Task
-	TaskDB.Where("project_id = ? AND user_id = ?", projectID, userID).Find(&tasks)
+	TaskDB.Where("project_id = ? AND user_id = ?", project.ID, project.UserID).Order("position, id").Find(&tasks)
+	attachChecklistProgress(TaskDB, tasks)
+	attachCustomFields(TaskDB, tasks)
+	renderDescriptions(c, tasks)
+
+	if c.Query("group_by") == "section" {
+		c.JSON(http.StatusOK, groupTasksBySection(ProjectDB, project.ID, tasks))
+		return
+	}
 	c.JSON(http.StatusOK, tasks)
 }
 
+
+// @Summary Get a project's kanban board
+// @Description Tasks grouped by status column, each column in position order.
+// @Tags Projects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.Board
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/board [get]
+func GetProjectBoard(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	projectID := c.Param("id")
+
+	var project models.Project
+	if err := visibleProjects(ProjectDB, userID).Where("id = ?", projectID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var tasks []models.Task
+	TaskDB.Where("project_id = ? AND user_id = ?", project.ID, project.UserID).Order("position, id").Find(&tasks)
+	attachChecklistProgress(TaskDB, tasks)
+	attachCustomFields(TaskDB, tasks)
+
+	board := models.Board{ProjectID: project.ID}
+	columns := make(map[string]int)
+	for _, status := range models.BoardStatuses {
+		columns[status] = len(board.Columns)
+		board.Columns = append(board.Columns, models.BoardColumn{Status: status, Tasks: []models.Task{}})
+	}
+	for _, task := range tasks {
+		i, ok := columns[task.Status]
+		if !ok {
+			i = len(board.Columns)
+			columns[task.Status] = i
+			board.Columns = append(board.Columns, models.BoardColumn{Status: task.Status, Tasks: []models.Task{}})
+		}
+		board.Columns[i].Tasks = append(board.Columns[i].Tasks, task)
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// @Summary List a project's members
+// @Description Members see the project and its tasks and can comment on and follow them, but only the owner edits. People join by accepting an invitation to the project.
+// @Tags Projects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} models.ProjectMember
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/members [get]
+func GetProjectMembers(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := visibleProjects(ProjectDB, userID).Where("id = ?", c.Param("id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	members := []models.ProjectMember{}
+	ProjectDB.Where("project_id = ?", project.ID).Order("id").Find(&members)
+	var users []models.User
+	ProjectDB.Where("id IN (SELECT user_id FROM project_members WHERE project_id = ?)", project.ID).Find(&users)
+	names := make(map[uint]string)
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+	for i := range members {
+		members[i].Username = names[members[i].UserID]
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// @Summary Remove a project member
+// @Description The owner can remove anyone, members can remove themselves. The member also stops following the project and the tasks in it they no longer see.
+// @Tags Projects
+// @Security BearerAuth
+// @Param id path int true "Project ID"
+// @Param user_id path int true "User ID of the member"
+// @Success 204
+// @Failure 403,404 {object} map[string]string
+// @Router /projects/{id}/members/{user_id} [delete]
+func RemoveProjectMember(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	memberID := uint(toInt(c.Param("user_id")))
+
+	var project models.Project
+	if err := visibleProjects(ProjectDB, userID).Where("id = ?", c.Param("id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if project.UserID != userID && memberID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the project owner can remove other members"})
+		return
+	}
+
+	err := ProjectDB.Transaction(func(tx *gorm.DB) error {
+		removed := tx.Where("project_id = ? AND user_id = ?", project.ID, memberID).Delete(&models.ProjectMember{})
+		if removed.Error != nil {
+			return removed.Error
+		}
+		if removed.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("user_id = ? AND project_id = ?", memberID, project.ID).Delete(&models.Watch{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND task_id IN (SELECT id FROM tasks WHERE project_id = ? AND user_id <> ? AND (assignee_id IS NULL OR assignee_id <> ?))",
+			memberID, project.ID, memberID, memberID).Delete(&models.Watch{}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// visibleProjects limits a query to projects the user owns or is a member of.
+func visibleProjects(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Where("(user_id = ? OR id IN (SELECT project_id FROM project_members WHERE user_id = ?))", userID, userID)
+}
+
+// isProjectMember reports whether the user is a member of the project.
+func isProjectMember(db *gorm.DB, projectID, userID uint) bool {
+	var count int64
+	db.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", projectID, userID).Count(&count)
+	return count > 0
+}
+
+// addProjectMember makes the user a member of the project, following it like
+// its owner does. Projects that no longer exist are skipped.
+func addProjectMember(db *gorm.DB, projectID, userID uint) error {
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if project.UserID == userID {
+		return nil
+	}
+	member := models.ProjectMember{ProjectID: projectID, UserID: userID}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+		return err
+	}
+	return watchProject(db, userID, projectID)
+}
+
+// publishProjectEvent tells subscribers about a newly created project.
+func publishProjectEvent(actorID uint, project models.Project) {
+	events.Publish(events.Event{
+		Type:      events.ProjectCreated,
+		ActorID:   actorID,
+		ProjectID: project.ID,
+		Data:      project,
+	})
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"go_task_api/events"
+	"go_task_api/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var ReminderDB *gorm.DB
+
+func InitReminder(db *gorm.DB) {
+	ReminderDB = db
+}
+
+// errReminderFired means another run fired the reminder first
+var errReminderFired = errors.New("reminder already fired")
+
+// @Summary List the reminders you set on a task
+// @Tags Reminders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {array} models.Reminder
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/reminders [get]
+func GetTaskReminders(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	task, err := findVisibleTask(ReminderDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	reminders := []models.Reminder{}
+	ReminderDB.Where("task_id = ? AND user_id = ?", task.ID, userID).Order("fire_at, id").Find(&reminders)
+	c.JSON(http.StatusOK, reminders)
+}
+
+// @Summary Set a reminder on a task
+// @Description Either at a fixed time (remind_at) or a number of minutes before the due date (minutes_before).
+// @Tags Reminders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param reminder body models.ReminderRequest true "When to remind you"
+// @Success 201 {object} models.Reminder
+// @Failure 400,404 {object} map[string]string
+// @Router /tasks/{id}/reminders [post]
+func CreateReminder(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	task, err := findVisibleTask(ReminderDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var input models.ReminderRequest
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if (input.RemindAt == nil) == (input.MinutesBefore == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set either remind_at or minutes_before"})
+		return
+	}
+	if input.MinutesBefore != nil && *input.MinutesBefore < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minutes_before can't be negative"})
+		return
+	}
+
+	reminder := models.Reminder{
+		TaskID:        task.ID,
+		UserID:        userID,
+		RemindAt:      input.RemindAt,
+		MinutesBefore: input.MinutesBefore,
+	}
+	reminder.FireAt = reminder.FireTime(task.DueDate)
+	ReminderDB.Create(&reminder)
+	c.JSON(http.StatusCreated, reminder)
+}
+
+// @Summary Delete one of your reminders
+// @Tags Reminders
+// @Security BearerAuth
+// @Param id path int true "Reminder ID"
+// @Success 204
+// @Router /reminders/{id} [delete]
+func DeleteReminder(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	ReminderDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.Reminder{})
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List your reminders that haven't fired yet, soonest first
+// @Description Relative reminders on tasks without a due date are listed last, with no fire_at.
+// @Tags Reminders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Reminder
+// @Router /reminders [get]
+func GetPendingReminders(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	reminders := []models.Reminder{}
+	ReminderDB.Where("user_id = ? AND fired_at IS NULL", userID).
+		Order("fire_at IS NULL, fire_at, id").Find(&reminders)
+	c.JSON(http.StatusOK, reminders)
+}
+
+// StartReminderScheduler fires due reminders every interval, until the
+// process exits. Reminders that came due while the server was down fire on
+// the first run.
+func StartReminderScheduler(interval time.Duration) {
+	go func() {
+		for {
+			fireReminders(ReminderDB, time.Now())
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// fireReminders sends every unfired reminder due by now through the
+// notification channels. The inbox notification is stored in the same
+// transaction that marks the reminder fired, so each reminder lands in the
+// inbox exactly once, even with overlapping runs or a crash in between.
+// Email and the live channels follow once the transaction has committed.
+func fireReminders(db *gorm.DB, now time.Time) int {
+	var reminders []models.Reminder
+	if err := db.Where("fired_at IS NULL AND fire_at <= ?", now).Order("fire_at, id").Find(&reminders).Error; err != nil {
+		log.Printf("loading reminders: %v", err)
+		return 0
+	}
+
+	fired := 0
+	for _, reminder := range reminders {
+		var task models.Task
+		found := db.First(&task, reminder.TaskID).Error == nil
+		event := events.Event{
+			Type:       events.TaskReminder,
+			ProjectID:  task.ProjectID,
+			TaskID:     task.ID,
+			Data:       task,
+			Recipients: []uint{reminder.UserID},
+			CreatedAt:  now,
+		}
+
+		// Reminders on deleted or closed tasks are marked fired without a notice
+		send := found && !task.IsClosed()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			claimed := tx.Model(&models.Reminder{}).Where("id = ? AND fired_at IS NULL", reminder.ID).Update("fired_at", now)
+			if claimed.Error != nil {
+				return claimed.Error
+			}
+			if claimed.RowsAffected == 0 {
+				return errReminderFired
+			}
+			if !send {
+				return nil
+			}
+			return storeNotifications(tx, event)
+		})
+		if err != nil {
+			if !errors.Is(err, errReminderFired) {
+				log.Printf("firing reminder %d: %v", reminder.ID, err)
+			}
+			continue
+		}
+		if send {
+			events.Publish(event)
+			fired++
+		}
+	}
+	return fired
+}
+
+// rescheduleReminders moves the unfired relative reminders of a task after
+// its due date changed.
+func rescheduleReminders(tx *gorm.DB, task models.Task) error {
+	var reminders []models.Reminder
+	if err := tx.Where("task_id = ? AND fired_at IS NULL AND minutes_before IS NOT NULL", task.ID).Find(&reminders).Error; err != nil {
+		return err
+	}
+	for _, reminder := range reminders {
+		if err := tx.Model(&reminder).Update("fire_at", reminder.FireTime(task.DueDate)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
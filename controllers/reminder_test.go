@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupReminderTestEnv() (*gin.Engine, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitWatch(db)
+	InitNotification(db)
+	InitReminder(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.PUT("/tasks/:id", UpdateTask)
+		auth.POST("/tasks/:id/reminders", CreateReminder)
+		auth.GET("/reminders", GetPendingReminders)
+		auth.GET("/notifications", GetNotifications)
+	}
+	return r, db
+}
+
+func TestReminders(t *testing.T) {
+	r, db := setupReminderTestEnv()
+	token := loginAs(r, t, "ann")
+
+	doJSON(r, "POST", "/tasks", token, `{"title": "File taxes", "due_date": "2025-05-10T17:00:00Z"}`)
+	if w := doJSON(r, "POST", "/tasks/1/reminders", token, `{}`); w.Code != 400 {
+		t.Fatalf("Expected 400 without a time, got %d", w.Code)
+	}
+	doJSON(r, "POST", "/tasks/1/reminders", token, `{"minutes_before": 60}`)
+	doJSON(r, "POST", "/tasks/1/reminders", token, `{"remind_at": "2025-05-09T09:00:00Z"}`)
+
+	var pending []models.Reminder
+	json.Unmarshal(doJSON(r, "GET", "/reminders", token, "").Body.Bytes(), &pending)
+	if len(pending) != 2 || pending[0].ID != 2 || !pending[1].FireAt.Equal(time.Date(2025, 5, 10, 16, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected pending reminders %+v", pending)
+	}
+
+	// Moving the due date moves the relative reminder only
+	doJSON(r, "PUT", "/tasks/1", token, `{"title": "File taxes", "due_date": "2025-05-12T17:00:00Z"}`)
+	var relative models.Reminder
+	db.First(&relative, 1)
+	if !relative.FireAt.Equal(time.Date(2025, 5, 12, 16, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the reminder to follow the due date, got %v", relative.FireAt)
+	}
+
+	now := time.Date(2025, 5, 11, 0, 0, 0, 0, time.UTC)
+	if n := fireReminders(db, now); n != 1 {
+		t.Fatalf("Expected 1 reminder to fire, got %d", n)
+	}
+	if n := fireReminders(db, now); n != 0 {
+		t.Fatalf("Expected reminders to fire once, got %d", n)
+	}
+
+	var list models.NotificationList
+	json.Unmarshal(doJSON(r, "GET", "/notifications", token, "").Body.Bytes(), &list)
+	if len(list.Notifications) != 1 || list.Notifications[0].Message != `Reminder: "File taxes"` {
+		t.Fatalf("Expected a reminder notification, got %+v", list)
+	}
+
+	pending = nil
+	json.Unmarshal(doJSON(r, "GET", "/reminders", token, "").Body.Bytes(), &pending)
+	if len(pending) != 1 || pending[0].ID != 1 {
+		t.Fatalf("Expected only the relative reminder pending, got %+v", pending)
+	}
+}
+
+func TestReminderStaysPendingWhenNotificationFails(t *testing.T) {
+	r, db := setupReminderTestEnv()
+	token := loginAs(r, t, "ann")
+
+	doJSON(r, "POST", "/tasks", token, `{"title": "File taxes"}`)
+	doJSON(r, "POST", "/tasks/1/reminders", token, `{"remind_at": "2025-05-09T09:00:00Z"}`)
+
+	now := time.Date(2025, 5, 10, 0, 0, 0, 0, time.UTC)
+	db.Migrator().DropTable(&models.Notification{})
+	if n := fireReminders(db, now); n != 0 {
+		t.Fatalf("Expected nothing to fire without an inbox, got %d", n)
+	}
+	var reminder models.Reminder
+	db.First(&reminder, 1)
+	if reminder.FiredAt != nil {
+		t.Fatalf("Expected the reminder to stay pending")
+	}
+
+	db.AutoMigrate(&models.Notification{})
+	if n := fireReminders(db, now); n != 1 {
+		t.Fatalf("Expected the reminder to fire on the next run, got %d", n)
+	}
+	var count int64
+	db.Model(&models.Notification{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("Expected exactly one notification, got %d", count)
+	}
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"errors"
+	"go_task_api/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var SectionDB *gorm.DB
+
+func InitSection(db *gorm.DB) {
+	SectionDB = db
+}
+
+// @Summary List a project's sections in order
+// @Tags Sections
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} models.Section
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/sections [get]
+func GetSections(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := visibleProjects(SectionDB, userID).Where("id = ?", c.Param("id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var sections []models.Section
+	SectionDB.Where("project_id = ?", project.ID).Order("position, id").Find(&sections)
+	c.JSON(http.StatusOK, sections)
+}
+
+// @Summary Add a section to the end of a project
+// @Tags Sections
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param section body models.SectionRequest true "Section name"
+// @Success 201 {object} models.Section
+// @Failure 400,404 {object} map[string]string
+// @Router /projects/{id}/sections [post]
+func CreateSection(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := SectionDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var input models.SectionRequest
+	if err := c.BindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	var maxPosition *int
+	SectionDB.Model(&models.Section{}).Where("project_id = ?", project.ID).
+		Select("MAX(position)").Scan(&maxPosition)
+
+	section := models.Section{ProjectID: project.ID, Name: strings.TrimSpace(input.Name)}
+	if maxPosition != nil {
+		section.Position = *maxPosition + 1
+	}
+	SectionDB.Create(&section)
+	c.JSON(http.StatusCreated, section)
+}
+
+// @Summary Reorder a project's sections
+// @Tags Sections
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param order body models.ReorderSectionsRequest true "All section IDs in their new order"
+// @Success 200 {array} models.Section
+// @Failure 400,404 {object} map[string]string
+// @Router /projects/{id}/sections [put]
+func ReorderSections(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := SectionDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var input models.ReorderSectionsRequest
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err := reorder(SectionDB, &models.Section{}, input.SectionIDs, "project_id = ?", project.ID)
+	if errors.Is(err, errIncompleteOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "section_ids must list every section exactly once"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder sections"})
+		return
+	}
+
+	var sections []models.Section
+	SectionDB.Where("project_id = ?", project.ID).Order("position, id").Find(&sections)
+	c.JSON(http.StatusOK, sections)
+}
+
+// @Summary Rename a section
+// @Tags Sections
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Section ID"
+// @Param section body models.SectionRequest true "New name"
+// @Success 200 {object} models.Section
+// @Failure 400,404 {object} map[string]string
+// @Router /sections/{id} [put]
+func UpdateSection(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	section, err := findUserSection(SectionDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
+		return
+	}
+
+	var input models.SectionRequest
+	if err := c.BindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	section.Name = strings.TrimSpace(input.Name)
+	SectionDB.Model(&section).Update("name", section.Name)
+	c.JSON(http.StatusOK, section)
+}
+
+// @Summary Delete a section
+// @Description Tasks in the section are kept and no longer belong to any section.
+// @Tags Sections
+// @Security BearerAuth
+// @Param id path int true "Section ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /sections/{id} [delete]
+func DeleteSection(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	section, err := findUserSection(SectionDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
+		return
+	}
+
+	SectionDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("section_id = ?", section.ID).Update("section_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&section).Error
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// findUserSection loads a section of one of the user's projects.
+func findUserSection(db *gorm.DB, id string, userID uint) (models.Section, error) {
+	var section models.Section
+	err := db.Joins("JOIN projects ON projects.id = sections.project_id").
+		Where("sections.id = ? AND projects.user_id = ?", id, userID).
+		First(&section).Error
+	return section, err
+}
+
+// checkSection makes sure a task can be put in the section.
+func checkSection(db *gorm.DB, sectionID *uint, projectID uint) error {
+	if sectionID == nil {
+		return nil
+	}
+	var section models.Section
+	if err := db.Where("id = ? AND project_id = ?", *sectionID, projectID).First(&section).Error; err != nil {
+		return errors.New("section must belong to the task's project")
+	}
+	return nil
+}
+
+// groupTasksBySection groups tasks by section in section order, followed by
+// the tasks without a section.
+func groupTasksBySection(db *gorm.DB, projectID uint, tasks []models.Task) []models.SectionGroup {
+	var sections []models.Section
+	db.Where("project_id = ?", projectID).Order("position, id").Find(&sections)
+
+	groups := make([]models.SectionGroup, 0, len(sections)+1)
+	index := make(map[uint]int, len(sections))
+	for i := range sections {
+		index[sections[i].ID] = len(groups)
+		groups = append(groups, models.SectionGroup{Section: &sections[i], Tasks: []models.Task{}})
+	}
+	unsectioned := models.SectionGroup{Tasks: []models.Task{}}
+
+	for _, task := range tasks {
+		if task.SectionID != nil {
+			if i, ok := index[*task.SectionID]; ok {
+				groups[i].Tasks = append(groups[i].Tasks, task)
+				continue
+			}
+		}
+		unsectioned.Tasks = append(unsectioned.Tasks, task)
+	}
+	return append(groups, unsectioned)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSectionTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitSection(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/projects", CreateProject)
+		auth.GET("/projects/:id/tasks", GetProjectTasks)
+		auth.POST("/projects/:id/sections", CreateSection)
+		auth.PUT("/projects/:id/sections", ReorderSections)
+		auth.DELETE("/sections/:id", DeleteSection)
+	}
+	return r
+}
+
+func TestProjectTasksGroupedBySection(t *testing.T) {
+	r := setupSectionTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Home"}`)
+	doJSON(r, "POST", "/projects/1/sections", token, `{"name": "Backlog"}`)
+	doJSON(r, "POST", "/projects/1/sections", token, `{"name": "This week"}`)
+	if w := doJSON(r, "PUT", "/projects/1/sections", token, `{"section_ids": [2, 1]}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d: %s", w.Code, w.Body.String())
+	}
+
+	doJSON(r, "POST", "/tasks", token, `{"title": "Paint fence", "project_id": 1, "section_id": 1}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Call plumber", "project_id": 1, "section_id": 2}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Someday", "project_id": 1}`)
+
+	w := doJSON(r, "GET", "/projects/1/tasks?group_by=section", token, "")
+	var groups []models.SectionGroup
+	if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(groups) != 3 || groups[0].Section.Name != "This week" || groups[2].Section != nil {
+		t.Fatalf("Unexpected groups: %+v", groups)
+	}
+	if groups[0].Tasks[0].Title != "Call plumber" || groups[2].Tasks[0].Title != "Someday" {
+		t.Fatalf("Unexpected grouping: %+v", groups)
+	}
+
+	doJSON(r, "DELETE", "/sections/2", token, "")
+	w = doJSON(r, "GET", "/projects/1/tasks?group_by=section", token, "")
+	groups = nil
+	json.Unmarshal(w.Body.Bytes(), &groups)
+	if len(groups) != 2 || len(groups[1].Tasks) != 2 {
+		t.Fatalf("Expected tasks of a deleted section to be kept, got %+v", groups)
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"go_task_api/models"
+	"go_task_api/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary List the devices you are logged in on
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Session
+// @Router /me/sessions [get]
+func GetSessions(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	sessions := []models.Session{}
+	DB.Where("user_id = ? AND revoked_at IS NULL AND last_used_at > ?", userID, time.Now().Add(-utils.RefreshTokenTTL)).
+		Order("last_used_at DESC, id DESC").Find(&sessions)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == claims.SessionID
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// @Summary Log out one of your devices
+// @Tags Auth
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /me/sessions/{id} [delete]
+func DeleteSession(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var session models.Session
+	if err := DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	revokeSession(DB, session.FamilyID)
+	c.Status(http.StatusNoContent)
+}
+
+// startSession records a new login from the requesting device
+func startSession(db *gorm.DB, userID uint, c *gin.Context) (models.Session, error) {
+	familyID, _, err := utils.NewOpaqueToken()
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	session := models.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		LastUsedAt: time.Now(),
+	}
+	err = db.Create(&session).Error
+	return session, err
+}
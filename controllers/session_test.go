@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	db := setupTestDB()
+	middlewares.InitRevocation(db)
+	t.Cleanup(func() { middlewares.InitRevocation(nil) })
+
+	r := setupRouter()
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.GET("/me/sessions", GetSessions)
+		auth.DELETE("/me/sessions/:id", DeleteSession)
+	}
+
+	loginFrom := func(userAgent string) string {
+		req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"username": "ann", "password": "secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var tokens models.TokenResponse
+		json.Unmarshal(w.Body.Bytes(), &tokens)
+		return tokens.Token
+	}
+	doJSON(r, "POST", "/register", "", `{"username": "ann", "password": "secret"}`)
+	laptop := loginFrom("Firefox on Linux")
+	phone := loginFrom("Safari on iPhone")
+
+	var sessions []models.Session
+	json.Unmarshal(doJSON(r, "GET", "/me/sessions", laptop, "").Body.Bytes(), &sessions)
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %+v", sessions)
+	}
+	for _, s := range sessions {
+		if s.Current != (s.UserAgent == "Firefox on Linux") || s.IP == "" {
+			t.Fatalf("Unexpected session %+v", s)
+		}
+	}
+
+	// Requests bump last-used, but at most once per interval
+	stale := time.Now().Add(-time.Hour)
+	db.Model(&models.Session{}).Where("id = 2").Update("last_used_at", stale)
+	doJSON(r, "GET", "/me/sessions", phone, "")
+	var session models.Session
+	db.First(&session, 2)
+	if !session.LastUsedAt.After(stale) {
+		t.Fatalf("Expected last-used to be updated")
+	}
+	db.Model(&models.Session{}).Where("id = 2").Update("last_used_at", stale)
+	doJSON(r, "GET", "/me/sessions", phone, "")
+	db.First(&session, 2)
+	if session.LastUsedAt.After(stale) {
+		t.Fatalf("Expected no write within the touch interval")
+	}
+
+	if w := doJSON(r, "DELETE", "/me/sessions/2", laptop, ""); w.Code != 204 {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	if w := doJSON(r, "GET", "/me/sessions", phone, ""); w.Code != 401 {
+		t.Fatalf("Expected the removed session's token to be refused, got %d", w.Code)
+	}
+	sessions = nil
+	json.Unmarshal(doJSON(r, "GET", "/me/sessions", laptop, "").Body.Bytes(), &sessions)
+	if len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("Expected only the current session left, got %+v", sessions)
+	}
+}
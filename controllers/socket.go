@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go_task_api/events"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"go_task_api/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+var SocketDB *gorm.DB
+
+// SocketBuffer is how many messages a client may fall behind before it is disconnected
+const SocketBuffer = 64
+
+// SocketTicketTTL is how long a ticket from POST /ws/ticket can be used
+var SocketTicketTTL = 30 * time.Second
+
+// SocketAuthCheck is how often open sockets check that the token they were
+// opened with hasn't been revoked
+var SocketAuthCheck = time.Minute
+
+// SocketOrigins are the browser origins, besides the API's own, allowed to
+// open sockets (WS_ALLOWED_ORIGINS, comma separated)
+var SocketOrigins = strings.FieldsFunc(os.Getenv("WS_ALLOWED_ORIGINS"), func(r rune) bool { return r == ',' || r == ' ' })
+
+func InitSocket(db *gorm.DB) {
+	SocketDB = db
+}
+
+type socketTicket struct {
+	claims    *utils.Claims
+	expiresAt time.Time
+}
+
+var (
+	ticketMu      sync.Mutex
+	socketTickets = make(map[string]socketTicket) // ticket hash -> ticket
+)
+
+// socketClient is one open WebSocket connection
+type socketClient struct {
+	user   models.Presence
+	claims *utils.Claims
+	conn   *websocket.Conn
+	send   chan models.SocketMessage
+
+	mu       sync.Mutex
+	projects map[uint]bool
+}
+
+var (
+	presenceMu sync.Mutex
+	viewers    = make(map[uint]map[*socketClient]struct{}) // project ID -> subscribed clients
+)
+
+// @Summary Get a ticket for opening a WebSocket from a browser
+// @Description The ticket opens one connection with GET /ws?ticket=..., within 30 seconds. The socket lives as long as the access token used here.
+// @Tags Events
+// @Security BearerAuth
+// @Produce json
+// @Success 201 {object} models.SocketTicket
+// @Router /ws/ticket [post]
+func CreateSocketTicket(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
+		return
+	}
+	now := time.Now()
+	ticket := socketTicket{claims: claims, expiresAt: now.Add(SocketTicketTTL)}
+
+	ticketMu.Lock()
+	for key, other := range socketTickets {
+		if now.After(other.expiresAt) {
+			delete(socketTickets, key)
+		}
+	}
+	socketTickets[hash] = ticket
+	ticketMu.Unlock()
+
+	c.JSON(http.StatusCreated, models.SocketTicket{Ticket: token, ExpiresAt: ticket.expiresAt})
+}
+
+// @Summary Open a WebSocket for live board editing
+// @Description Authenticate with a Bearer token, or from a browser with ?ticket= from POST /ws/ticket. Browsers may only connect from the API's own origin or one listed in WS_ALLOWED_ORIGINS. The socket is closed, after a "closed" message, when its token expires or is revoked.
+// @Description Send {"type": "subscribe", "project_id": 1} to receive the project's change events and presence updates. Moves ({"type": "move", "task_id": 7, "move": {...}}) and edits ({"type": "update", "task_id": 7, "task": {...}}) are validated exactly like POST /tasks/{id}/move and PUT /tasks/{id}, and answered with a reply carrying the same status and body.
+// @Tags Events
+// @Security BearerAuth
+// @Param ticket query string false "Ticket from POST /ws/ticket"
+// @Success 101
+// @Failure 401,403 {object} map[string]string
+// @Router /ws [get]
+func BoardSocket(c *gin.Context) {
+	claims, ok := socketClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	var user models.User
+	if err := SocketDB.First(&user, claims.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	server := websocket.Server{
+		Handshake: checkSocketOrigin,
+		Handler: func(conn *websocket.Conn) {
+			client := &socketClient{
+				user:     models.Presence{UserID: user.ID, Username: user.Username},
+				claims:   claims,
+				conn:     conn,
+				send:     make(chan models.SocketMessage, SocketBuffer),
+				projects: make(map[uint]bool),
+			}
+			client.serve()
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// socketClaims authenticates a socket request by its ticket or Bearer token.
+func socketClaims(c *gin.Context) (*utils.Claims, bool) {
+	var claims *utils.Claims
+	if ticket := c.Query("ticket"); ticket != "" {
+		hash := utils.HashToken(ticket)
+		ticketMu.Lock()
+		t, ok := socketTickets[hash]
+		delete(socketTickets, hash)
+		ticketMu.Unlock()
+		if !ok || time.Now().After(t.expiresAt) {
+			return nil, false
+		}
+		claims = t.claims
+	} else {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			return nil, false
+		}
+		var err error
+		if claims, err = utils.ParseToken(token); err != nil {
+			return nil, false
+		}
+	}
+	return claims, middlewares.StillValid(claims)
+}
+
+// checkSocketOrigin only lets browsers connect from the API's own origin or
+// one of SocketOrigins. Other clients don't send an Origin header.
+func checkSocketOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+	config.Origin = u
+	if strings.EqualFold(u.Host, req.Host) {
+		return nil
+	}
+	for _, allowed := range SocketOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s is not allowed", origin)
+}
+
+func (client *socketClient) serve() {
+	live, stop := events.Listen()
+	done := make(chan struct{})
+	defer func() {
+		stop()
+		client.leaveAll()
+		close(done)
+		client.conn.Close()
+	}()
+
+	go func() {
+		for {
+			select {
+			case msg := <-client.send:
+				if err := websocket.JSON.Send(client.conn, msg); err != nil {
+					client.conn.Close()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for e := range live {
+			if client.subscribed(e.ProjectID) && canSeeEvent(SocketDB, client.user.UserID, e) {
+				client.push(models.SocketMessage{Type: "event", ProjectID: e.ProjectID, Data: e})
+			}
+		}
+		// The bus dropped us for falling behind; the client reconnects and reloads
+		client.conn.Close()
+	}()
+
+	go client.watchToken(done)
+
+	for {
+		var req models.SocketRequest
+		if err := websocket.JSON.Receive(client.conn, &req); err != nil {
+			return
+		}
+		client.handle(req)
+	}
+}
+
+// watchToken closes the socket once its token expires or is revoked.
+func (client *socketClient) watchToken(done <-chan struct{}) {
+	check := time.NewTicker(SocketAuthCheck)
+	defer check.Stop()
+	var expired <-chan time.Time
+	if client.claims.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(client.claims.ExpiresAt.Time))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-check.C:
+			if middlewares.StillValid(client.claims) {
+				continue
+			}
+		case <-expired:
+		}
+		websocket.JSON.Send(client.conn, models.SocketMessage{Type: "closed", Error: "Token expired or revoked"})
+		client.conn.Close()
+		return
+	}
+}
+
+func (client *socketClient) handle(req models.SocketRequest) {
+	reply := models.SocketMessage{Type: "reply", ID: req.ID, Status: http.StatusOK}
+
+	switch req.Type {
+	case "ping":
+		reply.Data = "pong"
+	case "subscribe":
+		if !canSeeProject(SocketDB, client.user.UserID, req.ProjectID) {
+			reply.Status, reply.Error = http.StatusNotFound, "Project not found"
+			break
+		}
+		client.join(req.ProjectID)
+		reply.ProjectID = req.ProjectID
+	case "unsubscribe":
+		client.leave(req.ProjectID)
+		reply.ProjectID = req.ProjectID
+	case "move":
+		if req.Move == nil {
+			reply.Status, reply.Error = http.StatusBadRequest, "move is required"
+			break
+		}
+		body, _ := json.Marshal(req.Move)
+		reply.Status, reply.Data, reply.Error = runTaskHandler(MoveTask, client.user.UserID, req.TaskID, body)
+	case "update":
+		reply.Status, reply.Data, reply.Error = runTaskHandler(UpdateTask, client.user.UserID, req.TaskID, req.Task)
+	default:
+		reply.Status, reply.Error = http.StatusBadRequest, "Unknown message type"
+	}
+	client.push(reply)
+
+	if reply.ProjectID != 0 {
+		broadcastPresence(reply.ProjectID)
+	}
+}
+
+// push queues a message for the client, disconnecting clients that can't keep up
+func (client *socketClient) push(msg models.SocketMessage) {
+	select {
+	case client.send <- msg:
+	default:
+		client.conn.Close()
+	}
+}
+
+func (client *socketClient) subscribed(projectID uint) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.projects[projectID]
+}
+
+func (client *socketClient) join(projectID uint) {
+	client.mu.Lock()
+	client.projects[projectID] = true
+	client.mu.Unlock()
+
+	presenceMu.Lock()
+	if viewers[projectID] == nil {
+		viewers[projectID] = make(map[*socketClient]struct{})
+	}
+	viewers[projectID][client] = struct{}{}
+	presenceMu.Unlock()
+}
+
+func (client *socketClient) leave(projectID uint) {
+	client.mu.Lock()
+	delete(client.projects, projectID)
+	client.mu.Unlock()
+
+	presenceMu.Lock()
+	delete(viewers[projectID], client)
+	if len(viewers[projectID]) == 0 {
+		delete(viewers, projectID)
+	}
+	presenceMu.Unlock()
+}
+
+func (client *socketClient) leaveAll() {
+	client.mu.Lock()
+	var projects []uint
+	for id := range client.projects {
+		projects = append(projects, id)
+	}
+	client.mu.Unlock()
+
+	for _, id := range projects {
+		client.leave(id)
+		broadcastPresence(id)
+	}
+}
+
+// broadcastPresence sends everyone viewing a project the list of users viewing it
+func broadcastPresence(projectID uint) {
+	presenceMu.Lock()
+	seen := make(map[uint]bool)
+	users := []models.Presence{}
+	var clients []*socketClient
+	for client := range viewers[projectID] {
+		clients = append(clients, client)
+		if !seen[client.user.UserID] {
+			seen[client.user.UserID] = true
+			users = append(users, client.user)
+		}
+	}
+	presenceMu.Unlock()
+
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	for _, client := range clients {
+		client.push(models.SocketMessage{Type: "presence", ProjectID: projectID, Users: users})
+	}
+}
+
+// canSeeProject reports whether the user owns the project, is a member or is
+// assigned one of its tasks
+func canSeeProject(db *gorm.DB, userID, projectID uint) bool {
+	var count int64
+	visibleProjects(db.Model(&models.Project{}), userID).Where("id = ?", projectID).Count(&count)
+	if count == 0 {
+		db.Model(&models.Task{}).Where("project_id = ? AND assignee_id = ?", projectID, userID).Count(&count)
+	}
+	return count > 0
+}
+
+// runTaskHandler runs one of the task endpoints for a socket request, so edits
+// made over the socket get exactly the same validation as HTTP ones.
+func runTaskHandler(handler gin.HandlerFunc, userID, taskID uint, body []byte) (int, interface{}, string) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(taskID), 10)}}
+	c.Set("userID", userID)
+	handler(c)
+
+	if w.Code >= http.StatusBadRequest {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &failure)
+		return w.Code, nil, failure.Error
+	}
+	return w.Code, json.RawMessage(w.Body.Bytes()), ""
+}
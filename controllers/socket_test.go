@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSocketTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitWatch(db)
+	InitSocket(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+	r.GET("/ws", BoardSocket)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/projects", CreateProject)
+		auth.POST("/ws/ticket", CreateSocketTicket)
+	}
+	return r
+}
+
+func dialSocket(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	config, _ := websocket.NewConfig(strings.Replace(server.URL, "http", "ws", 1)+"/ws", server.URL)
+	config.Header.Set("Authorization", "Bearer "+token)
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	return conn
+}
+
+// nextMessage reads messages until one of the given type arrives
+func nextMessage(t *testing.T, conn *websocket.Conn, msgType string) models.SocketMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg models.SocketMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			t.Fatalf("Waiting for %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestBoardSocket(t *testing.T) {
+	r := setupSocketTestEnv()
+	ann := loginAs(r, t, "ann")
+	bob := loginAs(r, t, "bob")
+	doJSON(r, "POST", "/projects", ann, `{"name": "Launch"}`)
+	doJSON(r, "POST", "/projects", ann, `{"name": "Private"}`)
+	doJSON(r, "POST", "/tasks", ann, `{"title": "Write copy", "project_id": 1, "assignee_id": 2}`)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+	annConn := dialSocket(t, server, ann)
+	defer annConn.Close()
+	bobConn := dialSocket(t, server, bob)
+	defer bobConn.Close()
+
+	websocket.JSON.Send(annConn, models.SocketRequest{Type: "subscribe", ID: "a1", ProjectID: 1})
+	if reply := nextMessage(t, annConn, "reply"); reply.ID != "a1" || reply.Status != 200 {
+		t.Fatalf("Unexpected reply %+v", reply)
+	}
+	nextMessage(t, annConn, "presence")
+
+	websocket.JSON.Send(bobConn, models.SocketRequest{Type: "subscribe", ID: "b1", ProjectID: 2})
+	if reply := nextMessage(t, bobConn, "reply"); reply.Status != 404 {
+		t.Fatalf("Expected 404 for someone else's project, got %+v", reply)
+	}
+	websocket.JSON.Send(bobConn, models.SocketRequest{Type: "subscribe", ID: "b2", ProjectID: 1})
+	nextMessage(t, bobConn, "reply")
+	if presence := nextMessage(t, annConn, "presence"); len(presence.Users) != 2 || presence.Users[1].Username != "bob" {
+		t.Fatalf("Expected ann and bob viewing, got %+v", presence.Users)
+	}
+
+	// Moves are validated like POST /tasks/:id/move
+	websocket.JSON.Send(annConn, models.SocketRequest{Type: "move", ID: "a2", TaskID: 1, Move: &models.MoveTaskRequest{}})
+	if reply := nextMessage(t, annConn, "reply"); reply.Status != 400 || reply.Error == "" {
+		t.Fatalf("Expected a validation error, got %+v", reply)
+	}
+	websocket.JSON.Send(annConn, models.SocketRequest{Type: "move", ID: "a3", TaskID: 1, Move: &models.MoveTaskRequest{Status: "done"}})
+	reply := nextMessage(t, annConn, "reply")
+	var task models.Task
+	data, _ := json.Marshal(reply.Data)
+	json.Unmarshal(data, &task)
+	if reply.Status != 200 || task.Status != "done" {
+		t.Fatalf("Expected the task moved to done, got %+v", reply)
+	}
+
+	// Everyone viewing the project sees the change
+	if event := nextMessage(t, bobConn, "event"); event.ProjectID != 1 {
+		t.Fatalf("Unexpected event %+v", event)
+	}
+
+	bobConn.Close()
+	if presence := nextMessage(t, annConn, "presence"); len(presence.Users) != 1 || presence.Users[0].Username != "ann" {
+		t.Fatalf("Expected only ann viewing, got %+v", presence.Users)
+	}
+}
+
+func dialWithTicket(t *testing.T, r *gin.Engine, server *httptest.Server, token, origin string) (*websocket.Conn, error) {
+	var ticket models.SocketTicket
+	json.Unmarshal(doJSON(r, "POST", "/ws/ticket", token, "").Body.Bytes(), &ticket)
+	if ticket.Ticket == "" {
+		t.Fatalf("Expected a ticket")
+	}
+	wsURL := strings.Replace(server.URL, "http", "ws", 1) + "/ws?ticket=" + ticket.Ticket
+	conn, err := websocket.Dial(wsURL, "", origin)
+	if err == nil {
+		// A ticket opens one socket only
+		if again, err := websocket.Dial(wsURL, "", origin); err == nil {
+			again.Close()
+			t.Fatalf("Expected the ticket to be single use")
+		}
+	}
+	return conn, err
+}
+
+func TestSocketTicketsAndOrigins(t *testing.T) {
+	r := setupSocketTestEnv()
+	ann := loginAs(r, t, "ann")
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, err := dialWithTicket(t, r, server, ann, server.URL)
+	if err != nil {
+		t.Fatalf("Expected a same-origin browser to connect, got %v", err)
+	}
+	websocket.JSON.Send(conn, models.SocketRequest{Type: "ping", ID: "p1"})
+	if reply := nextMessage(t, conn, "reply"); reply.Data != "pong" {
+		t.Fatalf("Unexpected reply %+v", reply)
+	}
+	conn.Close()
+
+	if _, err := dialWithTicket(t, r, server, ann, "https://evil.example"); err == nil {
+		t.Fatalf("Expected a foreign origin to be refused")
+	}
+	SocketOrigins = []string{"https://board.example"}
+	defer func() { SocketOrigins = nil }()
+	conn, err = dialWithTicket(t, r, server, ann, "https://board.example")
+	if err != nil {
+		t.Fatalf("Expected an allowed origin to connect, got %v", err)
+	}
+	conn.Close()
+
+	if _, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1)+"/ws?ticket=bogus", "", server.URL); err == nil {
+		t.Fatalf("Expected an unknown ticket to be refused")
+	}
+}
+
+func TestSocketClosesOnRevocation(t *testing.T) {
+	r := setupSocketTestEnv()
+	ann := loginAs(r, t, "ann")
+	middlewares.InitRevocation(SocketDB)
+	SocketAuthCheck = 20 * time.Millisecond
+	t.Cleanup(func() {
+		middlewares.InitRevocation(nil)
+		SocketAuthCheck = time.Minute
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+	conn := dialSocket(t, server, ann)
+	defer conn.Close()
+
+	if err := revokeAllSessions(SocketDB, 1); err != nil {
+		t.Fatalf("Revoking sessions: %v", err)
+	}
+	nextMessage(t, conn, "closed")
+	var msg models.SocketMessage
+	if err := websocket.JSON.Receive(conn, &msg); err == nil {
+		t.Fatalf("Expected the socket to be closed, got %+v", msg)
+	}
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_task_api/events"
+	"go_task_api/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var StreamDB *gorm.DB
+
+// StreamHeartbeat is how often an idle stream gets a comment line, to keep
+// proxies from closing the connection
+var StreamHeartbeat = 15 * time.Second
+
+func InitStream(db *gorm.DB) {
+	StreamDB = db
+}
+
+// @Summary Stream task, project and comment changes as Server-Sent Events
+// @Description Only events about tasks and projects you can see are sent. Reconnect with the Last-Event-ID header to receive what you missed; a "reset" event means the missed events are no longer available and you should reload.
+// @Tags Events
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param project_id query []int false "Only events for these projects" collectionFormat(multi)
+// @Param Last-Event-ID header int false "Resume after this event"
+// @Success 200 {object} events.Event
+// @Router /events [get]
+func StreamEvents(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	projects := make(map[uint]bool)
+	for _, param := range c.QueryArray("project_id") {
+		for _, id := range strings.Split(param, ",") {
+			if n := toInt(strings.TrimSpace(id)); n > 0 {
+				projects[uint(n)] = true
+			}
+		}
+	}
+	visible := func(e events.Event) bool {
+		if len(projects) > 0 && !projects[e.ProjectID] {
+			return false
+		}
+		return canSeeEvent(StreamDB, userID, e)
+	}
+
+	// Listen before reading the log so nothing falls in between
+	live, stop := events.Listen()
+	defer stop()
+
+	var backlog []events.Event
+	resumed := true
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	var sent uint64
+	if lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		// When the log can't resume from the ID, e.g. one from before a
+		// restart that is ahead of the current count, only the replayed
+		// backlog counts as sent
+		if backlog, resumed = events.Since(id); resumed {
+			sent = id
+		}
+	} else {
+		sent = events.LastID()
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	if !resumed {
+		fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range backlog {
+		sent = e.ID
+		if visible(e) {
+			writeEvent(w, e)
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-live:
+			if !ok {
+				// Dropped for falling behind, the client reconnects and resumes
+				return
+			}
+			if e.ID <= sent {
+				continue
+			}
+			sent = e.ID
+			if visible(e) {
+				writeEvent(w, e)
+				w.Flush()
+			}
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+			w.Flush()
+		}
+	}
+}
+
+func writeEvent(w io.Writer, e events.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+// canSeeEvent reports whether the user may see an event: events addressed to
+// specific users go to those users only, the rest to whoever can see the task
+// or the project.
+func canSeeEvent(db *gorm.DB, userID uint, e events.Event) bool {
+	if len(e.Recipients) > 0 {
+		for _, id := range e.Recipients {
+			if id == userID {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch data := e.Data.(type) {
+	case models.Task:
+		return canSeeTask(db, data, userID)
+	case models.Project:
+		return data.UserID == userID || isProjectMember(db, data.ID, userID)
+	}
+	if e.TaskID == 0 {
+		return false
+	}
+	var task models.Task
+	if err := db.Select("id", "user_id", "assignee_id", "project_id").First(&task, e.TaskID).Error; err != nil {
+		return false
+	}
+	return canSeeTask(db, task, userID)
+}
+
+// canSeeTask is visibleTasks for a task already loaded
+func canSeeTask(db *gorm.DB, task models.Task, userID uint) bool {
+	if task.UserID == userID || (task.AssigneeID != nil && *task.AssigneeID == userID) {
+		return true
+	}
+	if task.ProjectID == 0 {
+		return false
+	}
+	var count int64
+	db.Model(&models.Project{}).Where("id = ? AND user_id = ?", task.ProjectID, task.UserID).Count(&count)
+	return count > 0 && isProjectMember(db, task.ProjectID, userID)
+}
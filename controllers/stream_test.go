@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"go_task_api/events"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupStreamTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitWatch(db)
+	InitStream(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/projects", CreateProject)
+		auth.GET("/events", StreamEvents)
+	}
+	return r
+}
+
+// streamFor reads the caller's event stream for a short while
+func streamFor(r *gin.Engine, token, query, lastEventID string, during func()) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/events"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(w, req)
+		close(done)
+	}()
+	if during != nil {
+		time.Sleep(50 * time.Millisecond)
+		during()
+	}
+	<-done
+	return w.Body.String()
+}
+
+func TestEventStream(t *testing.T) {
+	r := setupStreamTestEnv()
+	ann := loginAs(r, t, "ann")
+	bob := loginAs(r, t, "bob")
+
+	start := strconv.FormatUint(events.LastID(), 10)
+	doJSON(r, "POST", "/projects", ann, `{"name": "Home"}`)
+	doJSON(r, "POST", "/tasks", ann, `{"title": "Buy milk", "project_id": 1}`)
+	doJSON(r, "POST", "/tasks", bob, `{"title": "Secret plan"}`)
+
+	// Resuming replays what was missed, minus other people's tasks
+	body := streamFor(r, ann, "", start, nil)
+	if !strings.Contains(body, "event: project.created") || !strings.Contains(body, "Buy milk") || strings.Contains(body, "Secret plan") {
+		t.Fatalf("Unexpected replay %q", body)
+	}
+
+	// Live events are filtered by project
+	body = streamFor(r, ann, "?project_id=2", "", func() {
+		doJSON(r, "POST", "/tasks", ann, `{"title": "Walk dog", "project_id": 1}`)
+		doJSON(r, "POST", "/projects", ann, `{"name": "Garden"}`)
+	})
+	if strings.Contains(body, "Walk dog") || !strings.Contains(body, `"name":"Garden"`) {
+		t.Fatalf("Unexpected live stream %q", body)
+	}
+
+	// An ID the log doesn't know asks the client to reload
+	body = streamFor(r, ann, "", "999999999", func() {
+		doJSON(r, "POST", "/tasks", ann, `{"title": "Water plants", "project_id": 1}`)
+	})
+	if !strings.HasPrefix(body, "event: reset") {
+		t.Fatalf("Expected a reset, got %q", body)
+	}
+	if !strings.Contains(body, "Water plants") {
+		t.Fatalf("Expected live events after a reset, got %q", body)
+	}
+}
@@ -1,14 +1,19 @@
 package controllers
 
 import (
+	"errors"
 	"go_task_api/models"
 	"net/http"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 var TagDB *gorm.DB
 
+var errTagScopeConflict = errors.New("merged tags clash on a task")
+
 func InitTag(db *gorm.DB) {
 	TagDB = db
 }
@@ -26,6 +31,13 @@ func CreateTag(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if tag.ParentID != nil {
+		var parent models.Tag
+		if err := TagDB.First(&parent, *tag.ParentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent tag not found"})
+			return
+		}
+	}
 	TagDB.Create(&tag)
 	c.JSON(http.StatusCreated, tag)
 }
@@ -33,10 +45,221 @@ func CreateTag(c *gin.Context) {
 // @Summary Get all tags
 // @Tags Tags
 // @Produce json
+// @Param with_counts query bool false "Include open and closed task counts per tag"
 // @Success 200 {array} models.Tag
+// @Success 200 {array} models.TagWithCounts
 // @Router /tags [get]
 func GetTags(c *gin.Context) {
+	if c.Query("with_counts") == "true" {
+		var tags []models.TagWithCounts
+		TagDB.Table("tags").
+			Select("tags.id, tags.name, tags.parent_id, tags.scope, "+
+				"COUNT(CASE WHEN tasks.id IS NOT NULL AND tasks.status NOT IN ? THEN 1 END) AS open_count, "+
+				"COUNT(CASE WHEN tasks.status IN ? THEN 1 END) AS closed_count",
+				models.ClosedStatuses, models.ClosedStatuses).
+			Joins("LEFT JOIN task_tags ON task_tags.tag_id = tags.id").
+			Joins("LEFT JOIN tasks ON tasks.id = task_tags.task_id").
+			Group("tags.id").
+			Order("tags.id").
+			Scan(&tags)
+		c.JSON(http.StatusOK, tags)
+		return
+	}
+
 	var tags []models.Tag
 	TagDB.Find(&tags)
 	c.JSON(http.StatusOK, tags)
 }
+
+// @Summary Rename a tag
+// @Tags Tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Tag ID"
+// @Param input body models.RenameTagRequest true "New tag name"
+// @Success 200 {object} models.Tag
+// @Failure 400,403,404,409 {object} map[string]string
+// @Router /admin/tags/{id} [put]
+func RenameTag(c *gin.Context) {
+	id := c.Param("id")
+
+	var input models.RenameTagRequest
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	var tag models.Tag
+	if err := TagDB.First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	var existing models.Tag
+	if err := TagDB.Where("LOWER(name) = LOWER(?) AND id <> ?", input.Name, tag.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists, merge the tags instead", "tag_id": existing.ID})
+		return
+	}
+
+	tag.Name = input.Name
+	TagDB.Save(&tag)
+	c.JSON(http.StatusOK, tag)
+}
+
+// @Summary Merge tags into a target tag
+// @Description Re-points every task using one of the source tags to the target tag and deletes the source tags, in a single transaction. Fails if a task would end up with two tags from the same scope.
+// @Tags Tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Target tag ID"
+// @Param input body models.MergeTagsRequest true "Tags to merge into the target"
+// @Success 200 {object} models.Tag
+// @Failure 400,403,404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/tags/{id}/merge [post]
+func MergeTags(c *gin.Context) {
+	id := c.Param("id")
+
+	var input models.MergeTagsRequest
+	if err := c.BindJSON(&input); err != nil || len(input.SourceIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source_ids is required"})
+		return
+	}
+
+	var target models.Tag
+	if err := TagDB.First(&target, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	for _, sourceID := range input.SourceIDs {
+		if sourceID == target.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a tag into itself"})
+			return
+		}
+	}
+
+	var sources []models.Tag
+	TagDB.Where("id IN ?", input.SourceIDs).Find(&sources)
+	if len(sources) != len(uniqueIDs(input.SourceIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source tag not found"})
+		return
+	}
+	// Re-parenting the sources' children onto one of their own descendants
+	// would make a cycle
+	for _, source := range sources {
+		for _, id := range tagSubtreeIDs(TagDB, source.ID) {
+			if id == target.ID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a tag into one of its own subtags"})
+				return
+			}
+		}
+	}
+
+	var conflict string
+	err := TagDB.Transaction(func(tx *gorm.DB) error {
+		var taskIDs []uint
+		if err := tx.Table("task_tags").Where("tag_id IN ?", input.SourceIDs).
+			Distinct().Pluck("task_id", &taskIDs).Error; err != nil {
+			return err
+		}
+
+		// Link the target to every task that had a source tag but not the target yet
+		if err := tx.Exec(
+			"INSERT INTO task_tags (task_id, tag_id) "+
+				"SELECT DISTINCT task_id, ? FROM task_tags WHERE tag_id IN ? "+
+				"AND task_id NOT IN (SELECT task_id FROM task_tags WHERE tag_id = ?)",
+			target.ID, input.SourceIDs, target.ID,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM task_tags WHERE tag_id IN ?", input.SourceIDs).Error; err != nil {
+			return err
+		}
+		// The target may clash with a scoped tag the task already has
+		if len(taskIDs) > 0 {
+			var tasks []models.Task
+			if err := tx.Preload("Tags").Where("id IN ?", taskIDs).Find(&tasks).Error; err != nil {
+				return err
+			}
+			for _, task := range tasks {
+				if msg := checkTagScopes(task.Tags); msg != "" {
+					conflict = "Task " + task.Title + ": " + msg
+					return errTagScopeConflict
+				}
+			}
+		}
+		// Children of merged tags now hang off the target
+		if err := tx.Model(&models.Tag{}).Where("parent_id IN ?", input.SourceIDs).
+			Update("parent_id", target.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Tag{}, input.SourceIDs).Error
+	})
+	if errors.Is(err, errTagScopeConflict) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": conflict})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var out []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// tagSubtreeIDs returns the ID of the given tag and of all its descendants.
+func tagSubtreeIDs(db *gorm.DB, rootID uint) []uint {
+	ids := []uint{rootID}
+	frontier := []uint{rootID}
+	seen := map[uint]bool{rootID: true}
+	for len(frontier) > 0 {
+		var children []uint
+		db.Model(&models.Tag{}).Where("parent_id IN ?", frontier).Pluck("id", &children)
+		frontier = frontier[:0]
+		for _, id := range children {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return ids
+}
+
+// checkTagScopes returns an error message if more than one tag from the same
+// scope (e.g. "area::backend" and "area::frontend") is in tags.
+func checkTagScopes(tags []models.Tag) string {
+	used := make(map[string]string)
+	for _, tag := range tags {
+		scope := models.ScopeOf(tag.Name)
+		if scope == "" {
+			continue
+		}
+		if other, ok := used[scope]; ok && other != tag.Name {
+			return "Tags " + other + " and " + tag.Name + " are mutually exclusive"
+		}
+		used[scope] = tag.Name
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// setupTagTestEnv returns a token for the first account, which is the admin
+func setupTagTestEnv(t *testing.T) (*gin.Engine, *gorm.DB, string) {
+	db := setupTestDB()
+	InitTag(db)
+
+	r := setupRouter()
+	r.GET("/tags", GetTags)
+	admin := r.Group("/admin")
+	admin.Use(middlewares.AuthMiddleware(), middlewares.AdminOnly())
+	admin.PUT("/tags/:id", RenameTag)
+	admin.POST("/tags/:id/merge", MergeTags)
+	return r, db, loginAs(r, t, "boss")
+}
+
+func TestMergeTagsRepointsTasks(t *testing.T) {
+	r, db, admin := setupTagTestEnv(t)
+
+	urgent := models.Tag{Name: "urgent"}
+	upper := models.Tag{Name: "Urgent"}
+	shout := models.Tag{Name: "URGENT"}
+	db.Create(&urgent)
+	db.Create(&upper)
+	db.Create(&shout)
+
+	db.Create(&models.Task{Title: "a", Status: "todo", Tags: []models.Tag{urgent, upper}})
+	db.Create(&models.Task{Title: "b", Status: "done", Tags: []models.Tag{upper, shout}})
+
+	body, _ := json.Marshal(models.MergeTagsRequest{SourceIDs: []uint{upper.ID, shout.ID}})
+	if w := doJSON(r, "POST", "/admin/tags/1/merge", loginAs(r, t, "ann"), string(body)); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected only admins to merge tags, got %d", w.Code)
+	}
+	w := doJSON(r, "POST", "/admin/tags/1/merge", admin, string(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var tagCount int64
+	db.Model(&models.Tag{}).Count(&tagCount)
+	if tagCount != 1 {
+		t.Fatalf("Expected source tags to be deleted, %d tags left", tagCount)
+	}
+
+	w = doJSON(r, "GET", "/tags?with_counts=true", "", "")
+
+	var tags []models.TagWithCounts
+	if err := json.Unmarshal(w.Body.Bytes(), &tags); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(tags) != 1 || tags[0].OpenCount != 1 || tags[0].ClosedCount != 1 {
+		t.Fatalf("Unexpected counts: %+v", tags)
+	}
+}
+
+func TestRenameTagConflict(t *testing.T) {
+	r, db, admin := setupTagTestEnv(t)
+	db.Create(&models.Tag{Name: "urgent"})
+	db.Create(&models.Tag{Name: "todo"})
+
+	if w := doJSON(r, "PUT", "/admin/tags/2", loginAs(r, t, "ann"), `{"name": "later"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected only admins to rename tags, got %d", w.Code)
+	}
+	for _, name := range []string{"urgent", "URGENT"} {
+		if w := doJSON(r, "PUT", "/admin/tags/2", admin, `{"name": "`+name+`"}`); w.Code != http.StatusConflict {
+			t.Fatalf("Expected 409 renaming to %s, got %d", name, w.Code)
+		}
+	}
+	// Changing the case of a tag's own name is fine
+	if w := doJSON(r, "PUT", "/admin/tags/1", admin, `{"name": "Urgent"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+}
+
+func TestScopedTagsAndSubtree(t *testing.T) {
+	_, db, _ := setupTagTestEnv(t)
+
+	area := models.Tag{Name: "area"}
+	db.Create(&area)
+	backend := models.Tag{Name: "area::backend", ParentID: &area.ID}
+	frontend := models.Tag{Name: "area::frontend", ParentID: &area.ID}
+	db.Create(&backend)
+	db.Create(&frontend)
+	api := models.Tag{Name: "api", ParentID: &backend.ID}
+	db.Create(&api)
+
+	if backend.Scope != "area" {
+		t.Fatalf("Expected scope 'area', got %q", backend.Scope)
+	}
+	if msg := checkTagScopes([]models.Tag{backend, frontend}); msg == "" {
+		t.Fatalf("Expected tags from the same scope to be rejected")
+	}
+	if msg := checkTagScopes([]models.Tag{area, backend, api}); msg != "" {
+		t.Fatalf("Unexpected scope conflict: %s", msg)
+	}
+
+	ids := tagSubtreeIDs(db, area.ID)
+	if len(ids) != 4 {
+		t.Fatalf("Expected 4 tags in subtree, got %v", ids)
+	}
+}
+
+func TestMergeTagIntoSubtagRejected(t *testing.T) {
+	r, db, admin := setupTagTestEnv(t)
+
+	area := models.Tag{Name: "area"}
+	db.Create(&area)
+	backend := models.Tag{Name: "backend", ParentID: &area.ID}
+	db.Create(&backend)
+	api := models.Tag{Name: "api", ParentID: &backend.ID}
+	db.Create(&api)
+
+	if w := doJSON(r, "POST", "/admin/tags/3/merge", admin, `{"source_ids": [1]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", w.Code)
+	}
+	if ids := tagSubtreeIDs(db, area.ID); len(ids) != 3 {
+		t.Fatalf("Expected the tree to be unchanged, got %v", ids)
+	}
+}
+
+func TestMergeTagsKeepsScopesExclusive(t *testing.T) {
+	r, db, admin := setupTagTestEnv(t)
+
+	backend := models.Tag{Name: "area::backend"}
+	frontend := models.Tag{Name: "area::frontend"}
+	ui := models.Tag{Name: "ui"}
+	db.Create(&backend)
+	db.Create(&frontend)
+	db.Create(&ui)
+	db.Create(&models.Task{Title: "Login page", Status: "todo", Tags: []models.Tag{backend, ui}})
+
+	// Merging "ui" into area::frontend would give the task both areas
+	if w := doJSON(r, "POST", "/admin/tags/2/merge", admin, `{"source_ids": [3]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var task models.Task
+	db.Preload("Tags").First(&task)
+	if len(task.Tags) != 2 || db.First(&models.Tag{}, ui.ID).Error != nil {
+		t.Fatalf("Expected the merge to be rolled back, got %+v", task.Tags)
+	}
+}
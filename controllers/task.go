@@ -1,8 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"go_task_api/events"
 	"go_task_api/models"
+	"go_task_api/utils"
 	"net/http"
+	"strings"
+	"time"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"strconv"
@@ -27,17 +33,22 @@ func toInt(s string) int {
 // @Security BearerAuth
 // @Produce json
 // @Param project_id query int false "Filter by Project ID"
+// @Param tag_id query int false "Filter by tag, including all of its child tags"
 // @Param limit query int false "Max number of results"
 // @Param offset query int false "Number of results to skip"
-// @Param sort query string false "Sort by field (e.g. created_at, title, status)"
+// @Param sort query string false "Sort by field (e.g. created_at, title, status, or cf.<custom field name>)"
 // @Param order query string false "Sort order (asc or desc)"
+// @Param render query string false "Set to html to include sanitized description_html"
+// @Param cf.name query string false "Filter by the value of a custom field, e.g. cf.severity=high"
 // @Success 200 {array} models.Task
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Router /tasks [get]
 func GetTasks(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
 
 	projectID := c.Query("project_id")
+	tagID := c.Query("tag_id")
 	limit := c.DefaultQuery("limit", "10")
 	offset := c.DefaultQuery("offset", "0")
 	sort := c.DefaultQuery("sort", "created_at")
@@ -50,23 +61,76 @@ func GetTasks(c *gin.Context) {
 		query = query.Where("project_id = ?", projectID)
 	}
 
+	if tagID != "" {
+		tagIDs := tagSubtreeIDs(TaskDB, uint(toInt(tagID)))
+		query = query.Where("id IN (SELECT task_id FROM task_tags WHERE tag_id IN ?)", tagIDs)
+	}
+
+	query = filterByCustomFields(c, query)
+
 	if order != "asc" && order != "desc" {
 		order = "desc" // fallback
 	}
 
-	query = query.Order(sort + " " + order).
-		Limit(toInt(limit)).
+	if name, ok := strings.CutPrefix(sort, "cf."); ok {
+		var err error
+		if query, err = orderByCustomField(TaskDB, query, userID, projectID, name, order); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		query = query.Order(sort + " " + order)
+	}
+	query = query.Limit(toInt(limit)).
 		Offset(toInt(offset))
 
 	query.Find(&tasks)
+	attachChecklistProgress(TaskDB, tasks)
+	attachCustomFields(TaskDB, tasks)
+	renderDescriptions(c, tasks)
 	c.JSON(http.StatusOK, tasks)
 }
 
+// @Summary Get a single task
+// @Tags Tasks
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param render query string false "Set to html to include sanitized description_html"
+// @Success 200 {object} models.Task
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id} [get]
+func GetTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id := c.Param("id")
+
+	task, err := findVisibleTask(TaskDB.Preload("Tags"), id, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	tasks := []models.Task{task}
+	attachChecklistProgress(TaskDB, tasks)
+	attachCustomFields(TaskDB, tasks)
+	renderDescriptions(c, tasks)
+	c.JSON(http.StatusOK, tasks[0])
+}
+
 type CreateTaskInput struct {
-	Title     string `json:"title"`
-	Status    string `json:"status"`
-	ProjectID uint   `json:"project_id"`
-	TagIDs    []uint `json:"tag_ids"` // <-- accepts tag IDs
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	ProjectID   uint       `json:"project_id"`
+	TagIDs      []uint     `json:"tag_ids"` // <-- accepts tag IDs
+	ParentID    *uint      `json:"parent_id"`
+	DueDate     *time.Time `json:"due_date"`
+	MilestoneID *uint      `json:"milestone_id"`
+	SectionID   *uint      `json:"section_id"`
+	AssigneeID  *uint      `json:"assignee_id"`
+
+	CustomFields map[string]interface{} `json:"custom_fields"` // values keyed by field name
 }
 
 // @Summary Create a new task
@@ -93,21 +157,89 @@ func CreateTask(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag IDs"})
 			return
 		}
+		if msg := checkTagScopes(tags); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	if input.ParentID != nil {
+		var parent models.Task
+		if err := TaskDB.Where("id = ? AND user_id = ?", *input.ParentID, userID).First(&parent).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent task not found"})
+			return
+		}
 	}
 
 	task := models.Task{
-		Title:     input.Title,
-		Status:    input.Status,
-		ProjectID: input.ProjectID,
-		UserID:    userID,
-		Tags:      tags,
+		Title:       input.Title,
+		Description: input.Description,
+		Status:      input.Status,
+		ProjectID:   input.ProjectID,
+		UserID:      userID,
+		Tags:        tags,
+		ParentID:    input.ParentID,
+		DueDate:     input.DueDate,
+		MilestoneID: input.MilestoneID,
+		SectionID:   input.SectionID,
+		AssigneeID:  input.AssigneeID,
+	}
+
+	if err := checkMilestone(TaskDB, task.MilestoneID, task.ProjectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkSection(TaskDB, task.SectionID, task.ProjectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	if err := checkAssignee(TaskDB, task.AssigneeID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task.Position = nextPosition(TaskDB, userID, task.ProjectID, task.Status)
 
-	TaskDB.Create(&task)
-	c.JSON(http.StatusCreated, task)
+	values, err := validateCustomFields(TaskDB, task.ProjectID, input.CustomFields, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = TaskDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		if err := recordMilestoneChange(tx, task.ID, nil, task.MilestoneID); err != nil {
+			return err
+		}
+		// Creators and assignees follow the task automatically
+		if err := watchTask(tx, userID, task.ID); err != nil {
+			return err
+		}
+		if task.AssigneeID != nil {
+			if err := watchTask(tx, *task.AssigneeID, task.ID); err != nil {
+				return err
+			}
+		}
+		return storeCustomFields(tx, task.ID, values)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	tasks := []models.Task{task}
+	attachCustomFields(TaskDB, tasks)
+	publishTaskEvent(events.TaskCreated, userID, tasks[0])
+	if task.AssigneeID != nil && *task.AssigneeID != userID {
+		publishTaskEvent(events.TaskAssigned, userID, tasks[0])
+	}
+	c.JSON(http.StatusCreated, tasks[0])
 }
 
 // @Summary Update a task
+// @Description Changing the status or project puts the task at the bottom of its new board column; use /tasks/{id}/move to place it.
 // @Tags Tasks
 // @Security BearerAuth
 // @Accept json
@@ -129,21 +261,140 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
+	// Copy the current milestone, assignee and due date, binding would write through the pointers
+	var oldMilestoneID, oldAssigneeID *uint
+	var oldDueDate *time.Time
+	if task.DueDate != nil {
+		v := *task.DueDate
+		oldDueDate = &v
+	}
+	if task.MilestoneID != nil {
+		v := *task.MilestoneID
+		oldMilestoneID = &v
+	}
+	if task.AssigneeID != nil {
+		v := *task.AssigneeID
+		oldAssigneeID = &v
+	}
+	oldStatus, oldProjectID := task.Status, task.ProjectID
+
 	if err := c.BindJSON(&task); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	TaskDB.Save(&task)
-	c.JSON(http.StatusOK, task)
+	// A task changing board column goes to the bottom of the new one
+	columnChanged := task.Status != oldStatus || task.ProjectID != oldProjectID
+
+	// A new due date gets its own due-soon notice and moves relative reminders
+	dueDateChanged := (task.DueDate == nil) != (oldDueDate == nil) || (task.DueDate != nil && !task.DueDate.Equal(*oldDueDate))
+	if dueDateChanged {
+		task.DueSoonSentAt = nil
+	}
+
+	// The milestone must match the project, also when only the project changes
+	if task.MilestoneID != nil && (oldMilestoneID == nil || *oldMilestoneID != *task.MilestoneID || task.ProjectID != oldProjectID) {
+		if err := checkMilestone(TaskDB, task.MilestoneID, task.ProjectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := checkSection(TaskDB, task.SectionID, task.ProjectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	assigned := task.AssigneeID != nil && (oldAssigneeID == nil || *oldAssigneeID != *task.AssigneeID)
+	unassigned := oldAssigneeID != nil && (task.AssigneeID == nil || *task.AssigneeID != *oldAssigneeID)
+	if assigned {
+		if err := checkAssignee(TaskDB, task.AssigneeID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Tags sent with the update replace the task's current tags
+	replaceTags := task.Tags != nil
+	var tags []models.Tag
+	if replaceTags {
+		var ids []uint
+		for _, tag := range task.Tags {
+			ids = append(ids, tag.ID)
+		}
+		if len(ids) > 0 {
+			TaskDB.Where("id IN ?", ids).Find(&tags)
+		}
+		if msg := checkTagScopes(tags); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		task.Tags = nil
+	}
+	// Checklist items are managed through their own endpoints
+	task.Checklist = nil
+
+	values, err := validateCustomFields(TaskDB, task.ProjectID, task.CustomFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	task.CustomFields = nil
+
+	err = TaskDB.Transaction(func(tx *gorm.DB) error {
+		if columnChanged {
+			task.Position = nextPosition(tx, task.UserID, task.ProjectID, task.Status)
+		}
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+		if replaceTags {
+			if err := tx.Model(&task).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+			task.Tags = tags
+		}
+		if err := recordMilestoneChange(tx, task.ID, oldMilestoneID, task.MilestoneID); err != nil {
+			return err
+		}
+		if dueDateChanged {
+			if err := rescheduleReminders(tx, task); err != nil {
+				return err
+			}
+		}
+		if assigned {
+			if err := watchTask(tx, *task.AssigneeID, task.ID); err != nil {
+				return err
+			}
+		}
+		// The previous assignee can't see the task anymore, so stops watching it
+		if unassigned && *oldAssigneeID != task.UserID {
+			if err := tx.Where("user_id = ? AND task_id = ?", *oldAssigneeID, task.ID).Delete(&models.Watch{}).Error; err != nil {
+				return err
+			}
+		}
+		return storeCustomFields(tx, task.ID, values)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	tasks := []models.Task{task}
+	attachCustomFields(TaskDB, tasks)
+	publishTaskEvent(events.TaskUpdated, userID, tasks[0])
+	if assigned && *task.AssigneeID != userID {
+		publishTaskEvent(events.TaskAssigned, userID, tasks[0])
+	}
+	c.JSON(http.StatusOK, tasks[0])
 }
 
 // @Summary Delete a task
+// @Description Subtasks are kept and move up to the deleted task's parent.
 // @Tags Tasks
 // @Security BearerAuth
 // @Param id path int true "Task ID"
 // @Success 204
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
 // @Router /tasks/{id} [delete]
 func DeleteTask(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
@@ -154,6 +405,216 @@ func DeleteTask(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
-	TaskDB.Delete(&task)
+
+	err := TaskDB.Transaction(func(tx *gorm.DB) error {
+		for _, related := range []interface{}{
+			&models.ChecklistItem{},
+			&models.CustomFieldValue{},
+			&models.Comment{},
+			&models.Reminder{},
+		} {
+			if err := tx.Where("task_id = ?", task.ID).Delete(related).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&task).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		// Subtasks move up to the task's own parent
+		if err := tx.Model(&models.Task{}).Where("parent_id = ?", task.ID).Update("parent_id", task.ParentID).Error; err != nil {
+			return err
+		}
+		// The task leaves its milestone's scope
+		if err := recordMilestoneChange(tx, task.ID, task.MilestoneID, nil); err != nil {
+			return err
+		}
+		return tx.Delete(&task).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+
+	// Watches go after the event, so the task's watchers hear about it
+	publishTaskEvent(events.TaskDeleted, userID, task)
+	TaskDB.Where("task_id = ?", task.ID).Delete(&models.Watch{})
 	c.Status(http.StatusNoContent)
 }
+
+// @Summary Move a task on the board
+// @Description Changes the task's status column and position in one step.
+// @Tags Tasks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param move body models.MoveTaskRequest true "Target column and index"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/move [post]
+func MoveTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id := c.Param("id")
+
+	var input models.MoveTaskRequest
+	if err := c.BindJSON(&input); err != nil || input.Status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+		return
+	}
+
+	var task models.Task
+	if err := TaskDB.Where("id = ? AND user_id = ?", id, userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	err := TaskDB.Transaction(func(tx *gorm.DB) error {
+		var column []models.Task
+		tx.Select("id", "position").
+			Where("user_id = ? AND project_id = ? AND status = ? AND id <> ?", userID, task.ProjectID, input.Status, task.ID).
+			Order("position, id").
+			Find(&column)
+
+		index := input.Index
+		if index < 0 {
+			index = 0
+		}
+		if index > len(column) {
+			index = len(column)
+		}
+
+		position, ok := rankBetween(column, index)
+		if !ok {
+			// Neighbours are too close together, spread the column out again
+			for i := range column {
+				column[i].Position = models.PositionGap * float64(i+1)
+				if err := tx.Model(&models.Task{}).Where("id = ?", column[i].ID).
+					Update("position", column[i].Position).Error; err != nil {
+					return err
+				}
+			}
+			position, _ = rankBetween(column, index)
+		}
+
+		task.Status = input.Status
+		task.Position = position
+		return tx.Model(&task).Updates(map[string]interface{}{"status": task.Status, "position": task.Position}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move task"})
+		return
+	}
+
+	publishTaskEvent(events.TaskUpdated, userID, task)
+	c.JSON(http.StatusOK, task)
+}
+
+// rankBetween returns a position that sorts the task at index within column,
+// or false if the neighbours are too close together to fit one in between.
+func rankBetween(column []models.Task, index int) (float64, bool) {
+	switch {
+	case len(column) == 0:
+		return models.PositionGap, true
+	case index == 0:
+		return column[0].Position - models.PositionGap, true
+	case index == len(column):
+		return column[len(column)-1].Position + models.PositionGap, true
+	}
+
+	prev, next := column[index-1].Position, column[index].Position
+	mid := (prev + next) / 2
+	if mid <= prev || mid >= next {
+		return 0, false
+	}
+	return mid, true
+}
+
+// nextPosition returns a position at the bottom of the task's board column.
+func nextPosition(db *gorm.DB, userID, projectID uint, status string) float64 {
+	var maxPosition *float64
+	db.Model(&models.Task{}).
+		Where("user_id = ? AND project_id = ? AND status = ?", userID, projectID, status).
+		Select("MAX(position)").
+		Scan(&maxPosition)
+	if maxPosition == nil {
+		return models.PositionGap
+	}
+	return *maxPosition + models.PositionGap
+}
+
+// publishTaskEvent tells subscribers (notifications, webhooks, ...) about a task change.
+func publishTaskEvent(eventType string, actorID uint, task models.Task) {
+	events.Publish(events.Event{
+		Type:      eventType,
+		ActorID:   actorID,
+		ProjectID: task.ProjectID,
+		TaskID:    task.ID,
+		Data:      task,
+	})
+}
+
+// findVisibleTask loads a task the user owns, is assigned to or sees as a
+// member of its project.
+func findVisibleTask(db *gorm.DB, id interface{}, userID uint) (models.Task, error) {
+	var task models.Task
+	err := visibleTasks(db, userID).Where("id = ?", id).First(&task).Error
+	return task, err
+}
+
+// visibleTasks limits a query to tasks the user owns, is assigned to or sees
+// as a member of the project. Members only see the tasks of the project's
+// owner, not ones others filed under it.
+func visibleTasks(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Where("(user_id = ? OR assignee_id = ? OR "+memberTaskSQL+")", userID, userID, userID)
+}
+
+// memberTaskSQL matches tasks in a project the user, the argument, is a member of
+const memberTaskSQL = "tasks.project_id IN (SELECT project_members.project_id FROM project_members " +
+	"JOIN projects ON projects.id = project_members.project_id " +
+	"WHERE project_members.user_id = ? AND projects.user_id = tasks.user_id)"
+
+// checkAssignee makes sure tasks are only assigned to existing users.
+func checkAssignee(db *gorm.DB, assigneeID *uint) error {
+	if assigneeID == nil {
+		return nil
+	}
+	var user models.User
+	if err := db.First(&user, *assigneeID).Error; err != nil {
+		return errors.New("assignee not found")
+	}
+	return nil
+}
+
+// renderDescriptions fills in DescriptionHTML when the request asks for ?render=html.
+// Task references are only linked when the caller can see the referenced task.
+func renderDescriptions(c *gin.Context, tasks []models.Task) {
+	if c.Query("render") != "html" {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+
+	var refs []uint
+	for _, task := range tasks {
+		refs = append(refs, utils.TaskReferences(task.Description)...)
+	}
+	visible := make(map[uint]bool)
+	if len(refs) > 0 {
+		var ids []uint
+		visibleTasks(TaskDB.Model(&models.Task{}), userID).Where("id IN ?", refs).Pluck("id", &ids)
+		for _, id := range ids {
+			visible[id] = true
+		}
+	}
+
+	taskHref := func(id uint) string {
+		if !visible[id] {
+			return ""
+		}
+		return fmt.Sprintf("/tasks/%d", id)
+	}
+	for i := range tasks {
+		tasks[i].DescriptionHTML = utils.RenderMarkdown(tasks[i].Description, taskHref)
+	}
+}
@@ -17,7 +17,7 @@ import (
 
 func setupTaskTestEnv() *gin.Engine {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&models.User{}, &models.Task{})
+	db.AutoMigrate(models.All()...)
 	InitAuth(db)
 	InitTask(db)
 
@@ -29,10 +29,13 @@ func setupTaskTestEnv() *gin.Engine {
 
 	// Task routes (protected)
 	taskGroup := r.Group("/tasks")
-	taskGroup.Use(middleware.AuthMiddleware())
+	taskGroup.Use(middlewares.AuthMiddleware())
 	{
 		taskGroup.GET("", GetTasks)
 		taskGroup.POST("", CreateTask)
+		taskGroup.GET("/:id", GetTask)
+		taskGroup.PUT("/:id", UpdateTask)
+		taskGroup.DELETE("/:id", DeleteTask)
 	}
 
 	return r
@@ -97,3 +100,80 @@ func TestCreateAndGetTasks(t *testing.T) {
 		t.Fatalf("Unexpected tasks response: %+v", tasks)
 	}
 }
+
+func TestRankBetween(t *testing.T) {
+	column := []models.Task{{ID: 1, Position: 1024}, {ID: 2, Position: 2048}}
+
+	if p, ok := rankBetween(column, 1); !ok || p != 1536 {
+		t.Fatalf("Expected 1536, got %v (%v)", p, ok)
+	}
+	if p, ok := rankBetween(column, 0); !ok || p >= 1024 {
+		t.Fatalf("Expected a position before the first task, got %v", p)
+	}
+	if p, ok := rankBetween(column, 2); !ok || p <= 2048 {
+		t.Fatalf("Expected a position after the last task, got %v", p)
+	}
+
+	crowded := []models.Task{{ID: 1, Position: 0}, {ID: 2, Position: 0}}
+	if _, ok := rankBetween(crowded, 1); ok {
+		t.Fatalf("Expected tasks with equal positions to need a rebalance")
+	}
+}
+
+func TestRenderedLinksForAssignee(t *testing.T) {
+	r := setupTaskTestEnv()
+	owner := loginAs(r, t, "owner")
+	assignee := loginAs(r, t, "helper")
+
+	// Task 2 references task 1, and helper is assigned to both
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Order parts", "assignee_id": 2}`)
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Assemble", "description": "After #1", "assignee_id": 2}`)
+
+	w := doJSON(r, "GET", "/tasks/2?render=html", assignee, "")
+	var task models.Task
+	json.Unmarshal(w.Body.Bytes(), &task)
+	if w.Code != http.StatusOK || !bytes.Contains([]byte(task.DescriptionHTML), []byte(`href="/tasks/1"`)) {
+		t.Fatalf("Expected the assignee to get a link to task 1, got %d: %q", w.Code, task.DescriptionHTML)
+	}
+}
+
+func TestStatusChangeAppendsToColumn(t *testing.T) {
+	r := setupTaskTestEnv()
+	token := loginAs(r, t, "ann")
+
+	doJSON(r, "POST", "/tasks", token, `{"title": "Draft", "status": "todo"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Review", "status": "todo"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Ship", "status": "doing"}`)
+
+	var moved, ship models.Task
+	json.Unmarshal(doJSON(r, "PUT", "/tasks/1", token, `{"status": "doing"}`).Body.Bytes(), &moved)
+	json.Unmarshal(doJSON(r, "GET", "/tasks/3", token, "").Body.Bytes(), &ship)
+	if moved.Status != "doing" || moved.Position <= ship.Position {
+		t.Fatalf("Expected the task below %v in its new column, got %+v", ship.Position, moved)
+	}
+}
+
+func TestDeleteTaskKeepsSubtasks(t *testing.T) {
+	r := setupTaskTestEnv()
+	token := loginAs(r, t, "ann")
+	tag := models.Tag{Name: "home"}
+	TaskDB.Create(&tag)
+
+	doJSON(r, "POST", "/tasks", token, `{"title": "Move house"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Pack", "parent_id": 1, "tag_ids": [1]}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Buy boxes", "parent_id": 2}`)
+	if w := doJSON(r, "DELETE", "/tasks/2", token, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+
+	var boxes models.Task
+	TaskDB.First(&boxes, 3)
+	if boxes.ParentID == nil || *boxes.ParentID != 1 {
+		t.Fatalf("Expected the subtask to move up to task 1, got %v", boxes.ParentID)
+	}
+	var links int64
+	TaskDB.Table("task_tags").Where("task_id = ?", 2).Count(&links)
+	if links != 0 {
+		t.Fatalf("Expected the deleted task's tag links to go, %d left", links)
+	}
+}
@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"go_task_api/events"
+	"go_task_api/models"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var TemplateDB *gorm.DB
+
+func InitTemplate(db *gorm.DB) {
+	TemplateDB = db
+}
+
+// @Summary Save an existing project as a template
+// @Description Captures the project's tasks, subtasks, tags and due dates (as offsets from start_date).
+// @Tags Templates
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param input body models.SaveTemplateRequest false "Template name and start date"
+// @Success 201 {object} models.ProjectTemplate
+// @Failure 400,404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /projects/{id}/template [post]
+func SaveProjectAsTemplate(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := TemplateDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var input models.SaveTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+	if input.Name == "" {
+		input.Name = project.Name
+	}
+
+	var tasks []models.Task
+	TemplateDB.Preload("Tags").Where("project_id = ? AND user_id = ?", project.ID, userID).Order("id").Find(&tasks)
+
+	template := models.ProjectTemplate{UserID: userID, Kind: models.TemplateKindProject, Name: input.Name}
+	if err := saveTemplate(TemplateDB, &template, tasks, input.StartDate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// @Summary Save an existing task as a template
+// @Description Captures the task with its subtasks, tags and due dates (as offsets from start_date).
+// @Tags Templates
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param input body models.SaveTemplateRequest false "Template name and start date"
+// @Success 201 {object} models.ProjectTemplate
+// @Failure 400,404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tasks/{id}/template [post]
+func SaveTaskAsTemplate(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var task models.Task
+	if err := TemplateDB.Preload("Tags").Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	var input models.SaveTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+	if input.Name == "" {
+		input.Name = task.Title
+	}
+
+	// The task comes first, followed by its subtasks level by level
+	tasks := []models.Task{task}
+	frontier := []uint{task.ID}
+	for len(frontier) > 0 {
+		var children []models.Task
+		TemplateDB.Preload("Tags").Where("parent_id IN ? AND user_id = ?", frontier, userID).Order("id").Find(&children)
+		frontier = frontier[:0]
+		for _, child := range children {
+			tasks = append(tasks, child)
+			frontier = append(frontier, child.ID)
+		}
+	}
+
+	template := models.ProjectTemplate{UserID: userID, Kind: models.TemplateKindTask, Name: input.Name}
+	if err := saveTemplate(TemplateDB, &template, tasks, input.StartDate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// @Summary List your templates
+// @Tags Templates
+// @Security BearerAuth
+// @Produce json
+// @Param kind query string false "Only templates of this kind (project or task)"
+// @Success 200 {array} models.ProjectTemplate
+// @Router /templates [get]
+func GetTemplates(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	query := TemplateDB.Preload("Tasks").Where("user_id = ?", userID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var templates []models.ProjectTemplate
+	query.Find(&templates)
+	c.JSON(http.StatusOK, templates)
+}
+
+// @Summary Create a new project from a template
+// @Description Due dates are set relative to start_date.
+// @Tags Templates
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param input body models.InstantiateTemplateRequest true "Project name and start date"
+// @Success 201 {object} models.InstantiateTemplateResponse
+// @Failure 400,404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /projects/from-template/{id} [post]
+func InstantiateTemplate(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	template, err := findTemplate(TemplateDB, c.Param("id"), userID, models.TemplateKindProject)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	var input models.InstantiateTemplateRequest
+	if err := c.BindJSON(&input); err != nil || input.StartDate.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date is required"})
+		return
+	}
+	if input.Name == "" {
+		input.Name = template.Name
+	}
+
+	project := models.Project{Name: input.Name, UserID: userID}
+	var tasks []models.Task
+	err = TemplateDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		if err := watchProject(tx, userID, project.ID); err != nil {
+			return err
+		}
+		tasks, err = instantiateTemplateTasks(tx, template, project.ID, userID, input.StartDate)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project from template"})
+		return
+	}
+
+	publishProjectEvent(userID, project)
+	for _, task := range tasks {
+		publishTaskEvent(events.TaskCreated, userID, task)
+	}
+	c.JSON(http.StatusCreated, models.InstantiateTemplateResponse{Project: project, Tasks: tasks})
+}
+
+// @Summary Add a task from a task template to a project
+// @Description Due dates are set relative to start_date. The first task returned is the new top-level task.
+// @Tags Templates
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param input body models.InstantiateTaskTemplateRequest true "Project and start date"
+// @Success 201 {array} models.Task
+// @Failure 400,404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tasks/from-template/{id} [post]
+func InstantiateTaskTemplate(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	template, err := findTemplate(TemplateDB, c.Param("id"), userID, models.TemplateKindTask)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	var input models.InstantiateTaskTemplateRequest
+	if err := c.BindJSON(&input); err != nil || input.StartDate.IsZero() || input.ProjectID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id and start_date are required"})
+		return
+	}
+
+	var project models.Project
+	if err := TemplateDB.Where("id = ? AND user_id = ?", input.ProjectID, userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var tasks []models.Task
+	err = TemplateDB.Transaction(func(tx *gorm.DB) error {
+		tasks, err = instantiateTemplateTasks(tx, template, project.ID, userID, input.StartDate)
+		if err != nil || len(tasks) == 0 {
+			return err
+		}
+		// The new task goes to the bottom of its board column
+		root := &tasks[0]
+		root.Position = nextPosition(tx, userID, project.ID, root.Status)
+		if err := tx.Model(root).Update("position", root.Position).Error; err != nil {
+			return err
+		}
+		return watchTask(tx, userID, root.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task from template"})
+		return
+	}
+
+	for _, task := range tasks {
+		publishTaskEvent(events.TaskCreated, userID, task)
+	}
+	c.JSON(http.StatusCreated, tasks)
+}
+
+func findTemplate(db *gorm.DB, id string, userID uint, kind string) (models.ProjectTemplate, error) {
+	var template models.ProjectTemplate
+	err := db.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("id = ? AND user_id = ? AND kind = ?", id, userID, kind).First(&template).Error
+	return template, err
+}
+
+// saveTemplate stores template with a blueprint of each task. Due dates are
+// kept relative to start, or to the earliest due date when start is nil.
+func saveTemplate(db *gorm.DB, template *models.ProjectTemplate, tasks []models.Task, start *time.Time) error {
+	anchor := start
+	for _, task := range tasks {
+		if anchor == nil || (start == nil && task.DueDate != nil && task.DueDate.Before(*anchor)) {
+			anchor = task.DueDate
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+
+		created := make(map[uint]*models.TemplateTask, len(tasks))
+		template.Tasks = make([]models.TemplateTask, len(tasks))
+		for i, task := range tasks {
+			item := &template.Tasks[i]
+			*item = models.TemplateTask{
+				TemplateID:  template.ID,
+				Title:       task.Title,
+				Description: task.Description,
+				Status:      task.Status,
+				Position:    task.Position,
+				TagIDs:      []uint{},
+			}
+			for _, tag := range task.Tags {
+				item.TagIDs = append(item.TagIDs, tag.ID)
+			}
+			if task.DueDate != nil && anchor != nil {
+				days := int(math.Round(task.DueDate.Sub(*anchor).Hours() / 24))
+				item.DueOffsetDays = &days
+			}
+			if err := tx.Create(item).Error; err != nil {
+				return err
+			}
+			created[task.ID] = item
+		}
+
+		// Subtasks point at the template copy of their parent
+		for _, task := range tasks {
+			if task.ParentID == nil || created[*task.ParentID] == nil {
+				continue
+			}
+			item := created[task.ID]
+			item.ParentID = &created[*task.ParentID].ID
+			if err := tx.Model(item).Update("parent_id", item.ParentID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// instantiateTemplateTasks creates the template's tasks in a project, with due
+// dates counted from start.
+func instantiateTemplateTasks(tx *gorm.DB, template models.ProjectTemplate, projectID, userID uint, start time.Time) ([]models.Task, error) {
+	tasks := make([]models.Task, len(template.Tasks))
+	created := make(map[uint]*models.Task, len(template.Tasks))
+	for i, item := range template.Tasks {
+		task := &tasks[i]
+		*task = models.Task{
+			Title:       item.Title,
+			Description: item.Description,
+			Status:      item.Status,
+			Position:    item.Position,
+			ProjectID:   projectID,
+			UserID:      userID,
+		}
+		if item.DueOffsetDays != nil {
+			due := start.AddDate(0, 0, *item.DueOffsetDays)
+			task.DueDate = &due
+		}
+		if len(item.TagIDs) > 0 {
+			// Tags deleted since the template was saved are skipped
+			tx.Where("id IN ?", item.TagIDs).Find(&task.Tags)
+		}
+		if err := tx.Create(task).Error; err != nil {
+			return nil, err
+		}
+		created[item.ID] = task
+	}
+
+	for _, item := range template.Tasks {
+		if item.ParentID == nil || created[*item.ParentID] == nil {
+			continue
+		}
+		task := created[item.ID]
+		task.ParentID = &created[*item.ParentID].ID
+		if err := tx.Model(task).Update("parent_id", task.ParentID).Error; err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/events"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTemplateTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitTag(db)
+	InitCustomField(db)
+	InitTemplate(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+	r.POST("/tags", CreateTag)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/projects", CreateProject)
+		auth.POST("/projects/:id/template", SaveProjectAsTemplate)
+		auth.POST("/projects/from-template/:id", InstantiateTemplate)
+		auth.POST("/tasks/:id/template", SaveTaskAsTemplate)
+		auth.POST("/tasks/from-template/:id", InstantiateTaskTemplate)
+	}
+	return r
+}
+
+func TestSaveAndInstantiateTemplate(t *testing.T) {
+	r := setupTemplateTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/tags", token, `{"name": "onboarding"}`)
+	doJSON(r, "POST", "/projects", token, `{"name": "Onboarding"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Laptop", "project_id": 1, "tag_ids": [1], "due_date": "2025-05-05T09:00:00Z"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Install tools", "project_id": 1, "parent_id": 1, "due_date": "2025-05-08T09:00:00Z"}`)
+
+	if w := doJSON(r, "POST", "/projects/1/template", token, `{"name": "New hire"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d: %s", w.Code, w.Body.String())
+	}
+
+	mark := events.LastID()
+	w := doJSON(r, "POST", "/projects/from-template/1", token, `{"name": "Onboarding: Alex", "start_date": "2025-06-02T09:00:00Z"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp models.InstantiateTemplateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp.Project.ID != 2 || len(resp.Tasks) != 2 {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+
+	// Like a project made by hand, it is watched and its tasks announced
+	if err := TemplateDB.Where("user_id = ? AND project_id = ?", 1, resp.Project.ID).First(&models.Watch{}).Error; err != nil {
+		t.Fatalf("Expected the new project to be watched: %v", err)
+	}
+	published, _ := events.Since(mark)
+	created := 0
+	for _, e := range published {
+		if e.Type == events.TaskCreated && e.ProjectID == resp.Project.ID {
+			created++
+		}
+	}
+	if created != 2 {
+		t.Fatalf("Expected an event per task, got %d", created)
+	}
+
+	laptop, tools := resp.Tasks[0], resp.Tasks[1]
+	if len(laptop.Tags) != 1 || laptop.Tags[0].Name != "onboarding" {
+		t.Fatalf("Expected tags to be copied, got %+v", laptop.Tags)
+	}
+	if tools.ParentID == nil || *tools.ParentID != laptop.ID {
+		t.Fatalf("Expected subtask to point at the new parent, got %+v", tools.ParentID)
+	}
+	want := time.Date(2025, 6, 5, 9, 0, 0, 0, time.UTC)
+	if tools.DueDate == nil || !tools.DueDate.Equal(want) {
+		t.Fatalf("Expected due date %v, got %v", want, tools.DueDate)
+	}
+}
+
+func TestSaveAndInstantiateTaskTemplate(t *testing.T) {
+	r := setupTemplateTestEnv()
+	token := registerAndLogin(r, t)
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Releases"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Cut release", "project_id": 1, "due_date": "2025-05-10T09:00:00Z"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Tag build", "project_id": 1, "parent_id": 1, "due_date": "2025-05-08T09:00:00Z"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Unrelated", "project_id": 1}`)
+
+	w := doJSON(r, "POST", "/tasks/1/template", token, "")
+	var template models.ProjectTemplate
+	json.Unmarshal(w.Body.Bytes(), &template)
+	if w.Code != http.StatusCreated || template.Kind != models.TemplateKindTask || len(template.Tasks) != 2 {
+		t.Fatalf("Expected a task template with the task and its subtask, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := doJSON(r, "POST", "/projects/from-template/1", token, `{"start_date": "2025-06-02T09:00:00Z"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected task templates not to create projects, got %d", w.Code)
+	}
+
+	w = doJSON(r, "POST", "/tasks/from-template/1", token, `{"project_id": 1, "start_date": "2025-06-02T09:00:00Z"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d: %s", w.Code, w.Body.String())
+	}
+	var tasks []models.Task
+	json.Unmarshal(w.Body.Bytes(), &tasks)
+	if len(tasks) != 2 || tasks[0].Title != "Cut release" || tasks[0].ProjectID != 1 {
+		t.Fatalf("Unexpected tasks: %+v", tasks)
+	}
+	if tasks[1].ParentID == nil || *tasks[1].ParentID != tasks[0].ID {
+		t.Fatalf("Expected subtask to point at the new task, got %+v", tasks[1].ParentID)
+	}
+	want := time.Date(2025, 6, 4, 9, 0, 0, 0, time.UTC)
+	if tasks[0].DueDate == nil || !tasks[0].DueDate.Equal(want) {
+		t.Fatalf("Expected due date %v, got %v", want, tasks[0].DueDate)
+	}
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"errors"
+	"go_task_api/models"
+	"go_task_api/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errRefreshReused = errors.New("refresh token reused")
+
+// @Summary Exchange a refresh token for a new access and refresh token
+// @Description The refresh token can only be used once. Presenting it again revokes every token issued from the same login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.BindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	var current models.RefreshToken
+	if err := DB.Where("token_hash = ?", utils.HashToken(req.RefreshToken)).First(&current).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	var tokens models.TokenResponse
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// Only the first exchange of a token can mark it used
+		claimed := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", current.ID).
+			Update("used_at", time.Now())
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			return errRefreshReused
+		}
+		if err := tx.Model(&models.Session{}).Where("family_id = ?", current.FamilyID).
+			Update("last_used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		tokens, err = issueTokens(tx, current.UserID, current.FamilyID)
+		return err
+	})
+	if errors.Is(err, errRefreshReused) {
+		// Whoever holds the rotated-out token may have stolen it
+		revokeSession(DB, current.FamilyID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used, please log in again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary Log out
+// @Description Revokes the access token used for this request and the refresh token of the same login.
+// @Tags Auth
+// @Security BearerAuth
+// @Success 204
+// @Router /logout [post]
+func Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	if claims.ID != "" {
+		revoked := models.RevokedToken{JTI: claims.ID, UserID: claims.UserID}
+		if claims.ExpiresAt != nil {
+			revoked.ExpiresAt = claims.ExpiresAt.Time
+		}
+		DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked)
+	}
+	if claims.SessionID != "" {
+		revokeSession(DB, claims.SessionID)
+	}
+	// Revocations are only needed until the tokens would have expired anyway
+	DB.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{})
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Log out everywhere
+// @Description Revokes every access and refresh token you were issued, on all devices.
+// @Tags Auth
+// @Security BearerAuth
+// @Success 204
+// @Router /logout-all [post]
+func LogoutAll(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	if err := revokeAllSessions(DB, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// revokeAllSessions invalidates all of a user's access tokens by bumping
+// their token version, and revokes all of their refresh tokens.
+func revokeAllSessions(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// issueTokens creates an access token and a refresh token for one of the
+// user's sessions.
+func issueTokens(db *gorm.DB, userID uint, familyID string) (models.TokenResponse, error) {
+	var user models.User
+	if err := db.Select("id", "username", "role", "token_version").First(&user, userID).Error; err != nil {
+		return models.TokenResponse{}, err
+	}
+	refresh, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	access, err := utils.GenerateToken(utils.Claims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		SessionID:    familyID,
+		TokenVersion: user.TokenVersion,
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return models.TokenResponse{}, err
+	}
+	return models.TokenResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresAt:    time.Now().Add(utils.AccessTokenTTL).UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// revokeSession ends one login: its refresh tokens stop working and so do its
+// access tokens, which AuthMiddleware checks against the session.
+func revokeSession(db *gorm.DB, familyID string) {
+	now := time.Now()
+	db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now)
+	db.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now)
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	db := setupTestDB()
+	r := setupRouter()
+	r.POST("/token/refresh", RefreshToken)
+
+	doJSON(r, "POST", "/register", "", `{"username": "ann", "password": "secret"}`)
+	var login models.TokenResponse
+	json.Unmarshal(doJSON(r, "POST", "/login", "", `{"username": "ann", "password": "secret"}`).Body.Bytes(), &login)
+	if login.Token == "" || login.RefreshToken == "" {
+		t.Fatalf("Expected an access and a refresh token, got %+v", login)
+	}
+
+	w := doJSON(r, "POST", "/token/refresh", "", `{"refresh_token": "`+login.RefreshToken+`"}`)
+	var rotated models.TokenResponse
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	if w.Code != 200 || rotated.RefreshToken == "" || rotated.RefreshToken == login.RefreshToken {
+		t.Fatalf("Expected a rotated refresh token, got %d %+v", w.Code, rotated)
+	}
+
+	var stored models.RefreshToken
+	db.Last(&stored)
+	if stored.TokenHash == rotated.RefreshToken {
+		t.Fatalf("Refresh tokens must be stored hashed")
+	}
+
+	// Replaying the old token revokes the whole family, including the new token
+	if w := doJSON(r, "POST", "/token/refresh", "", `{"refresh_token": "`+login.RefreshToken+`"}`); w.Code != 401 {
+		t.Fatalf("Expected 401 on reuse, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/token/refresh", "", `{"refresh_token": "`+rotated.RefreshToken+`"}`); w.Code != 401 {
+		t.Fatalf("Expected the family to be revoked, got %d", w.Code)
+	}
+
+	// Expired tokens are refused
+	json.Unmarshal(doJSON(r, "POST", "/login", "", `{"username": "ann", "password": "secret"}`).Body.Bytes(), &login)
+	db.Model(&models.RefreshToken{}).Where("revoked_at IS NULL").Update("expires_at", time.Now().Add(-time.Minute))
+	if w := doJSON(r, "POST", "/token/refresh", "", `{"refresh_token": "`+login.RefreshToken+`"}`); w.Code != 401 {
+		t.Fatalf("Expected 401 for an expired token, got %d", w.Code)
+	}
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	db := setupTestDB()
+	middlewares.InitRevocation(db)
+	t.Cleanup(func() { middlewares.InitRevocation(nil) })
+
+	r := setupRouter()
+	r.POST("/token/refresh", RefreshToken)
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/logout", Logout)
+		auth.POST("/logout-all", LogoutAll)
+		auth.GET("/me/watching", GetWatching)
+	}
+	admin := r.Group("/admin")
+	admin.Use(middlewares.AuthMiddleware(), middlewares.AdminOnly())
+	admin.POST("/users/:id/revoke-sessions", AdminRevokeSessions)
+	admin.PUT("/users/:id/role", AdminUpdateUserRole)
+	InitWatch(db)
+
+	login := func(username string) models.TokenResponse {
+		var tokens models.TokenResponse
+		json.Unmarshal(doJSON(r, "POST", "/login", "", `{"username": "`+username+`", "password": "secret"}`).Body.Bytes(), &tokens)
+		return tokens
+	}
+	// The first account becomes the admin
+	doJSON(r, "POST", "/register", "", `{"username": "boss", "password": "secret"}`)
+	doJSON(r, "POST", "/register", "", `{"username": "ann", "password": "secret"}`)
+
+	// Logging out revokes that token and its login only
+	laptop, phone := login("ann"), login("ann")
+	if w := doJSON(r, "POST", "/logout", laptop.Token, ""); w.Code != 204 {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	if w := doJSON(r, "GET", "/me/watching", laptop.Token, ""); w.Code != 401 {
+		t.Fatalf("Expected the logged out token to be refused, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/token/refresh", "", `{"refresh_token": "`+laptop.RefreshToken+`"}`); w.Code != 401 {
+		t.Fatalf("Expected the logged out refresh token to be refused, got %d", w.Code)
+	}
+	if w := doJSON(r, "GET", "/me/watching", phone.Token, ""); w.Code != 200 {
+		t.Fatalf("Expected the other session to keep working, got %d", w.Code)
+	}
+
+	// Logging out everywhere revokes every session
+	laptop = login("ann")
+	doJSON(r, "POST", "/logout-all", phone.Token, "")
+	for _, token := range []string{phone.Token, laptop.Token} {
+		if w := doJSON(r, "GET", "/me/watching", token, ""); w.Code != 401 {
+			t.Fatalf("Expected all tokens to be refused, got %d", w.Code)
+		}
+	}
+	if w := doJSON(r, "GET", "/me/watching", login("ann").Token, ""); w.Code != 200 {
+		t.Fatalf("Expected a new login to work, got %d", w.Code)
+	}
+
+	// Admins can do the same for anyone
+	phone = login("ann")
+	boss := login("boss")
+	if w := doJSON(r, "POST", "/admin/users/2/revoke-sessions", boss.Token, ""); w.Code != 204 {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/token/refresh", "", `{"refresh_token": "`+phone.RefreshToken+`"}`); w.Code != 401 {
+		t.Fatalf("Expected the refresh token to be revoked, got %d", w.Code)
+	}
+
+	// Changing a role retires the tokens carrying the old one
+	if w := doJSON(r, "PUT", "/admin/users/1/role", boss.Token, `{"role": "user"}`); w.Code != 200 {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/admin/users/2/revoke-sessions", boss.Token, ""); w.Code != 401 {
+		t.Fatalf("Expected the stale admin token to be refused, got %d", w.Code)
+	}
+	var refreshed models.TokenResponse
+	json.Unmarshal(doJSON(r, "POST", "/token/refresh", "", `{"refresh_token": "`+boss.RefreshToken+`"}`).Body.Bytes(), &refreshed)
+	if w := doJSON(r, "POST", "/admin/users/2/revoke-sessions", refreshed.Token, ""); w.Code != 403 {
+		t.Fatalf("Expected the refreshed token to carry the new role, got %d", w.Code)
+	}
+}
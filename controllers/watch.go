@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"go_task_api/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var WatchDB *gorm.DB
+
+func InitWatch(db *gorm.DB) {
+	WatchDB = db
+}
+
+// @Summary Watch a task
+// @Tags Watching
+// @Security BearerAuth
+// @Param id path int true "Task ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/watch [post]
+func WatchTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	task, err := findVisibleTask(WatchDB, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	watchTask(WatchDB, userID, task.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Stop watching a task
+// @Tags Watching
+// @Security BearerAuth
+// @Param id path int true "Task ID"
+// @Success 204
+// @Router /tasks/{id}/watch [delete]
+func UnwatchTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	WatchDB.Where("user_id = ? AND task_id = ?", userID, c.Param("id")).Delete(&models.Watch{})
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Watch every task of a project
+// @Description Open to the project's owner and members.
+// @Tags Watching
+// @Security BearerAuth
+// @Param id path int true "Project ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/watch [post]
+func WatchProject(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var project models.Project
+	if err := visibleProjects(WatchDB, userID).Where("id = ?", c.Param("id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	watchProject(WatchDB, userID, project.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Stop watching a project
+// @Tags Watching
+// @Security BearerAuth
+// @Param id path int true "Project ID"
+// @Success 204
+// @Router /projects/{id}/watch [delete]
+func UnwatchProject(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	WatchDB.Where("user_id = ? AND project_id = ?", userID, c.Param("id")).Delete(&models.Watch{})
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List the tasks and projects you are watching
+// @Tags Watching
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.Watching
+// @Router /me/watching [get]
+func GetWatching(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	// Watches on tasks and projects the user can no longer see are left out
+	watching := models.Watching{Tasks: []models.Task{}, Projects: []models.Project{}}
+	visibleTasks(WatchDB, userID).
+		Where("id IN (SELECT task_id FROM watches WHERE user_id = ? AND task_id <> 0)", userID).
+		Order("id").Find(&watching.Tasks)
+	WatchDB.Where("id IN (SELECT project_id FROM watches WHERE user_id = ? AND project_id <> 0)", userID).
+		Where("user_id = ? OR id IN (SELECT project_id FROM tasks WHERE assignee_id = ?) OR id IN (SELECT project_id FROM project_members WHERE user_id = ?)", userID, userID, userID).
+		Order("id").Find(&watching.Projects)
+	c.JSON(http.StatusOK, watching)
+}
+
+func watchTask(db *gorm.DB, userID, taskID uint) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Watch{UserID: userID, TaskID: taskID}).Error
+}
+
+func watchProject(db *gorm.DB, userID, projectID uint) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Watch{UserID: userID, ProjectID: projectID}).Error
+}
+
+// taskWatcherIDs returns everyone watching the task directly or through its project.
+func taskWatcherIDs(db *gorm.DB, task models.Task) []uint {
+	var ids []uint
+	db.Model(&models.Watch{}).
+		Where("task_id = ? OR (project_id <> 0 AND project_id = ?)", task.ID, task.ProjectID).
+		Distinct().
+		Pluck("user_id", &ids)
+	return ids
+}
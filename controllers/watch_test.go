@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupWatchTestEnv() *gin.Engine {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitComment(db)
+	InitWatch(db)
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.PUT("/tasks/:id", UpdateTask)
+		auth.POST("/projects", CreateProject)
+		auth.POST("/tasks/:id/comments", CreateComment)
+		auth.DELETE("/tasks/:id/watch", UnwatchTask)
+		auth.GET("/me/watching", GetWatching)
+	}
+	return r
+}
+
+func loginAs(r *gin.Engine, t *testing.T, username string) string {
+	body := `{"username": "` + username + `", "password": "secret"}`
+	for _, path := range []string{"/register", "/login"} {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if path == "/login" {
+			var resp map[string]string
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp["token"] == "" {
+				t.Fatalf("Login failed for %s: %d", username, w.Code)
+			}
+			return resp["token"]
+		}
+	}
+	return ""
+}
+
+func TestAutomaticWatching(t *testing.T) {
+	r := setupWatchTestEnv()
+	owner := loginAs(r, t, "owner")
+	helper := loginAs(r, t, "helper")
+
+	doJSON(r, "POST", "/projects", owner, `{"name": "Move house"}`)
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Book van", "project_id": 1, "assignee_id": 2}`)
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Pack books", "project_id": 1}`)
+
+	var watching models.Watching
+	json.Unmarshal(doJSON(r, "GET", "/me/watching", owner, "").Body.Bytes(), &watching)
+	if len(watching.Tasks) != 2 || len(watching.Projects) != 1 {
+		t.Fatalf("Expected creator to watch tasks and project, got %+v", watching)
+	}
+
+	watching = models.Watching{}
+	json.Unmarshal(doJSON(r, "GET", "/me/watching", helper, "").Body.Bytes(), &watching)
+	if len(watching.Tasks) != 1 || watching.Tasks[0].Title != "Book van" {
+		t.Fatalf("Expected assignee to watch the assigned task, got %+v", watching)
+	}
+
+	doJSON(r, "DELETE", "/tasks/1/watch", helper, "")
+	if w := doJSON(r, "POST", "/tasks/1/comments", helper, `{"body": "Booked for Saturday"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected assignee to be able to comment, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/tasks/2/comments", helper, `{"body": "Hi"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 on someone else's task, got %d", w.Code)
+	}
+
+	watching = models.Watching{}
+	json.Unmarshal(doJSON(r, "GET", "/me/watching", helper, "").Body.Bytes(), &watching)
+	if len(watching.Tasks) != 1 {
+		t.Fatalf("Expected commenter to watch the task again, got %+v", watching)
+	}
+}
+
+func TestReassigningTaskDropsOldAssigneeWatch(t *testing.T) {
+	r := setupWatchTestEnv()
+	owner := loginAs(r, t, "owner")
+	first := loginAs(r, t, "first")
+	loginAs(r, t, "second")
+
+	doJSON(r, "POST", "/projects", owner, `{"name": "Move house"}`)
+	doJSON(r, "POST", "/tasks", owner, `{"title": "Book van", "project_id": 1, "assignee_id": 2}`)
+	if w := doJSON(r, "PUT", "/tasks/1", owner, `{"assignee_id": 3}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	TaskDB.Model(&models.Watch{}).Where("user_id = ? AND task_id = ?", 2, 1).Count(&count)
+	if count != 0 {
+		t.Fatalf("Expected the old assignee's watch to be removed")
+	}
+
+	// A watch left over from before is not enough to see the task
+	watchTask(TaskDB, 2, 1)
+	var watching models.Watching
+	json.Unmarshal(doJSON(r, "GET", "/me/watching", first, "").Body.Bytes(), &watching)
+	if len(watching.Tasks) != 0 {
+		t.Fatalf("Expected tasks the user can't see to be hidden, got %+v", watching.Tasks)
+	}
+}
@@ -0,0 +1,464 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_task_api/events"
+	"go_task_api/models"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var WebhookDB *gorm.DB
+
+// WebhookClient sends webhook requests. It doesn't follow redirects, and
+// refuses to connect to loopback, private and link-local addresses whatever
+// the URL's host resolves to at the time.
+var WebhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: checkWebhookDial,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// WebhookAllowPrivate lets webhooks reach loopback, private and link-local
+// addresses, for receivers on the internal network (WEBHOOK_ALLOW_PRIVATE=true)
+var WebhookAllowPrivate = os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+
+var errPrivateAddress = errors.New("webhooks can't be sent to loopback, private or link-local addresses")
+
+// Retry schedule: the n-th failed attempt waits WebhookRetryBase * 2^(n-1),
+// up to WebhookMaxDelay, and the delivery is given up after WebhookMaxAttempts.
+var (
+	WebhookMaxAttempts = 8
+	WebhookRetryBase   = 30 * time.Second
+	WebhookMaxDelay    = 6 * time.Hour
+)
+
+const webhookTestEvent = "webhook.test"
+
+var (
+	subscribeWebhooks sync.Once
+	wakeWebhooks      = make(chan struct{}, 1)
+)
+
+func InitWebhook(db *gorm.DB) {
+	WebhookDB = db
+	subscribeWebhooks.Do(func() {
+		events.Subscribe(enqueueWebhooks)
+	})
+}
+
+// @Summary Create a webhook
+// @Description Covers one project when project_id is set, otherwise every task and project you own.
+// @Tags Webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookRequest true "Webhook"
+// @Success 201 {object} models.Webhook
+// @Failure 400,404 {object} map[string]string
+// @Router /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.WebhookRequest
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL must be an absolute http or https URL"})
+		return
+	}
+	if err := checkWebhookHost(c.Request.Context(), u.Hostname()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input.EventTypes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Choose at least one event type"})
+		return
+	}
+	for _, eventType := range input.EventTypes {
+		if !isEventType(eventType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown event type %q", eventType)})
+			return
+		}
+	}
+	if input.ProjectID != nil {
+		var project models.Project
+		if err := WebhookDB.Where("id = ? AND user_id = ?", *input.ProjectID, userID).First(&project).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+	}
+
+	hook := models.Webhook{
+		UserID:     userID,
+		ProjectID:  input.ProjectID,
+		URL:        input.URL,
+		Secret:     input.Secret,
+		EventTypes: uniqueStrings(input.EventTypes),
+	}
+	if hook.Secret == "" {
+		hook.Secret = randomHex(32)
+	}
+	if err := WebhookDB.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+	c.JSON(http.StatusCreated, hook)
+}
+
+// @Summary List your webhooks
+// @Tags Webhooks
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Router /webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	hooks := []models.Webhook{}
+	WebhookDB.Where("user_id = ?", userID).Order("id").Find(&hooks)
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// @Summary Delete a webhook and its delivery log
+// @Tags Webhooks
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+	WebhookDB.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{})
+	WebhookDB.Delete(&hook)
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List a webhook's deliveries, newest first
+// @Tags Webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param status query string false "pending, succeeded or failed"
+// @Param limit query int false "Max number of results (default 50)"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	query := WebhookDB.Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	deliveries := []models.WebhookDelivery{}
+	query.Order("id DESC").Limit(toInt(c.DefaultQuery("limit", "50"))).Find(&deliveries)
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Send a test event to a webhook
+// @Description The first attempt is made right away; a failed test is retried like any other delivery.
+// @Tags Webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 404 {object} map[string]string
+// @Router /webhooks/{id}/test [post]
+func TestWebhook(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	payload, _ := json.Marshal(events.Event{
+		Type:      webhookTestEvent,
+		ActorID:   hook.UserID,
+		Data:      gin.H{"message": "This is a test event"},
+		CreatedAt: time.Now(),
+	})
+	delivery := models.WebhookDelivery{
+		WebhookID: hook.ID,
+		EventType: webhookTestEvent,
+		Payload:   string(payload),
+		Status:    models.DeliveryPending,
+	}
+	WebhookDB.Create(&delivery)
+	attemptDelivery(WebhookDB, hook, &delivery, time.Now())
+	c.JSON(http.StatusOK, delivery)
+}
+
+func findWebhook(c *gin.Context) (models.Webhook, bool) {
+	userID := c.MustGet("userID").(uint)
+
+	var hook models.Webhook
+	if err := WebhookDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&hook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return hook, false
+	}
+	return hook, true
+}
+
+// StartWebhookWorker sends queued deliveries whenever new ones are queued and
+// at least every interval, until the process exits. The queue lives in the
+// database, so deliveries pending at shutdown are sent after a restart.
+func StartWebhookWorker(interval time.Duration) {
+	go func() {
+		for {
+			processDeliveries(WebhookDB, time.Now())
+			select {
+			case <-wakeWebhooks:
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// enqueueWebhooks queues the event for every webhook that covers it.
+func enqueueWebhooks(e events.Event) {
+	db := WebhookDB
+	ownerID := eventOwner(db, e)
+
+	query := db.Where("user_id = ? AND project_id IS NULL", ownerID)
+	if e.ProjectID != 0 {
+		query = db.Where("project_id = ?", e.ProjectID).Or("user_id = ? AND project_id IS NULL", ownerID)
+	}
+	var hooks []models.Webhook
+	query.Find(&hooks)
+
+	var payload []byte
+	now := time.Now()
+	queued := false
+	for _, hook := range hooks {
+		if !containsString(hook.EventTypes, e.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(e); err != nil {
+				log.Printf("encoding %s webhook payload: %v", e.Type, err)
+				return
+			}
+		}
+		delivery := models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventType:     e.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			log.Printf("queueing webhook delivery: %v", err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case wakeWebhooks <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// eventOwner returns the owner of the task or project an event is about
+func eventOwner(db *gorm.DB, e events.Event) uint {
+	switch data := e.Data.(type) {
+	case models.Task:
+		return data.UserID
+	case models.Project:
+		return data.UserID
+	}
+	var task models.Task
+	if e.TaskID != 0 && db.Select("user_id").First(&task, e.TaskID).Error == nil {
+		return task.UserID
+	}
+	return 0
+}
+
+// processDeliveries makes an attempt at every pending delivery that is due.
+func processDeliveries(db *gorm.DB, now time.Time) {
+	var deliveries []models.WebhookDelivery
+	db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).Order("id").Find(&deliveries)
+
+	for i := range deliveries {
+		var hook models.Webhook
+		if err := db.First(&hook, deliveries[i].WebhookID).Error; err != nil {
+			db.Delete(&deliveries[i])
+			continue
+		}
+		attemptDelivery(db, hook, &deliveries[i], now)
+	}
+}
+
+// attemptDelivery posts the payload once and records the outcome, scheduling
+// a retry with exponential backoff when it fails.
+func attemptDelivery(db *gorm.DB, hook models.Webhook, delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+	delivery.StatusCode = 0
+	delivery.Error = ""
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewBufferString(delivery.Payload))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "go-task-api-webhooks")
+		req.Header.Set("X-Webhook-Event", delivery.EventType)
+		req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+		req.Header.Set("X-Webhook-Signature", "sha256="+signPayload(hook.Secret, delivery.Payload))
+
+		var resp *http.Response
+		if resp, err = WebhookClient.Do(req); err == nil {
+			resp.Body.Close()
+			delivery.StatusCode = resp.StatusCode
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		}
+	}
+
+	if err == nil {
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	} else {
+		delivery.Error = err.Error()
+		if delivery.Attempts >= WebhookMaxAttempts {
+			delivery.Status = models.DeliveryFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(retryDelay(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+	}
+	db.Save(delivery)
+}
+
+// checkWebhookHost resolves a webhook's host and refuses it if any of its
+// addresses is off limits.
+func checkWebhookHost(ctx context.Context, host string) error {
+	if WebhookAllowPrivate {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("Could not resolve %s", host)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// checkWebhookDial runs before every connection the webhook client makes,
+// with the address actually dialed, so redirects and DNS answers that change
+// after the URL was checked can't reach internal addresses either.
+func checkWebhookDial(network, address string, _ syscall.RawConn) error {
+	if WebhookAllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := WebhookRetryBase
+	for i := 1; i < attempts && delay < WebhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > WebhookMaxDelay {
+		delay = WebhookMaxDelay
+	}
+	return delay
+}
+
+// signPayload returns the hex HMAC-SHA256 of the payload, which receivers
+// compare against the X-Webhook-Signature header
+func signPayload(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_task_api/events"
+	"go_task_api/middlewares"
+	"go_task_api/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupWebhookTestEnv() (*gin.Engine, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(models.All()...)
+	InitAuth(db)
+	InitTask(db)
+	InitProject(db)
+	InitWatch(db)
+	InitWebhook(db)
+	// Test receivers listen on loopback
+	WebhookAllowPrivate = true
+
+	r := gin.Default()
+	r.POST("/register", Register)
+	r.POST("/login", Login)
+
+	auth := r.Group("/")
+	auth.Use(middlewares.AuthMiddleware())
+	{
+		auth.POST("/tasks", CreateTask)
+		auth.POST("/projects", CreateProject)
+		auth.POST("/webhooks", CreateWebhook)
+		auth.GET("/webhooks", GetWebhooks)
+		auth.GET("/webhooks/:id/deliveries", GetWebhookDeliveries)
+		auth.POST("/webhooks/:id/test", TestWebhook)
+	}
+	return r, db
+}
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (rec *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, string(body))
+	w.WriteHeader(rec.status)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	r, db := setupWebhookTestEnv()
+	token := loginAs(r, t, "ann")
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	if w := doJSON(r, "POST", "/webhooks", token, `{"url": "ftp://example.com", "event_types": ["task.created"]}`); w.Code != 400 {
+		t.Fatalf("Expected 400 for a non-http URL, got %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/webhooks", token, `{"url": "`+server.URL+`", "event_types": ["task.exploded"]}`); w.Code != 400 {
+		t.Fatalf("Expected 400 for an unknown event type, got %d", w.Code)
+	}
+	w := doJSON(r, "POST", "/webhooks", token, `{"url": "`+server.URL+`", "secret": "s3cr3t", "event_types": ["task.created", "project.created"]}`)
+	if w.Code != 201 {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	doJSON(r, "POST", "/projects", token, `{"name": "Home"}`)
+	doJSON(r, "POST", "/tasks", token, `{"title": "Buy milk", "project_id": 1}`)
+	processDeliveries(db, time.Now())
+
+	if len(receiver.requests) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(receiver.requests))
+	}
+	req, body := receiver.requests[1], receiver.bodies[1]
+	if req.Header.Get("X-Webhook-Event") != "task.created" {
+		t.Fatalf("Unexpected event header %q", req.Header.Get("X-Webhook-Event"))
+	}
+	if req.Header.Get("X-Webhook-Signature") != "sha256="+signPayload("s3cr3t", body) {
+		t.Fatalf("Bad signature %q", req.Header.Get("X-Webhook-Signature"))
+	}
+	var e events.Event
+	if err := json.Unmarshal([]byte(body), &e); err != nil || e.Type != "task.created" || e.TaskID != 1 {
+		t.Fatalf("Unexpected payload %s", body)
+	}
+
+	var hooks []models.Webhook
+	json.Unmarshal(doJSON(r, "GET", "/webhooks", token, "").Body.Bytes(), &hooks)
+	if len(hooks) != 1 || hooks[0].Secret != "" {
+		t.Fatalf("Expected the secret to be hidden, got %+v", hooks)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	r, db := setupWebhookTestEnv()
+	token := loginAs(r, t, "ann")
+	receiver := &webhookReceiver{status: http.StatusBadGateway}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	doJSON(r, "POST", "/webhooks", token, `{"url": "`+server.URL+`", "event_types": ["task.created"]}`)
+
+	var delivery models.WebhookDelivery
+	json.Unmarshal(doJSON(r, "POST", "/webhooks/1/test", token, "").Body.Bytes(), &delivery)
+	if delivery.Status != models.DeliveryPending || delivery.StatusCode != 502 || delivery.Attempts != 1 {
+		t.Fatalf("Expected a failed first attempt, got %+v", delivery)
+	}
+
+	// Each retry waits twice as long as the one before
+	now := *delivery.NextAttemptAt
+	for attempt := 2; attempt <= WebhookMaxAttempts; attempt++ {
+		processDeliveries(db, now.Add(-time.Second))
+		db.First(&delivery, delivery.ID)
+		if delivery.Attempts != attempt-1 {
+			t.Fatalf("Retried before the backoff elapsed")
+		}
+		processDeliveries(db, now)
+		delivery = models.WebhookDelivery{ID: delivery.ID}
+		db.First(&delivery)
+		if delivery.Attempts != attempt {
+			t.Fatalf("Expected attempt %d, got %d", attempt, delivery.Attempts)
+		}
+		if delivery.NextAttemptAt != nil {
+			if got := delivery.NextAttemptAt.Sub(now); got != retryDelay(attempt) {
+				t.Fatalf("Expected a %v backoff, got %v", retryDelay(attempt), got)
+			}
+			now = *delivery.NextAttemptAt
+		}
+	}
+	if delivery.Status != models.DeliveryFailed {
+		t.Fatalf("Expected the delivery to be given up, got %+v", delivery)
+	}
+
+	var log []models.WebhookDelivery
+	json.Unmarshal(doJSON(r, "GET", "/webhooks/1/deliveries?status=failed", token, "").Body.Bytes(), &log)
+	if len(log) != 1 || log[0].EventType != "webhook.test" {
+		t.Fatalf("Expected the failed test delivery in the log, got %+v", log)
+	}
+}
+
+func TestWebhookPrivateAddressesAndRedirects(t *testing.T) {
+	r, _ := setupWebhookTestEnv()
+	token := loginAs(r, t, "ann")
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusFound))
+	defer redirect.Close()
+
+	doJSON(r, "POST", "/webhooks", token, `{"url": "`+server.URL+`", "event_types": ["task.created"]}`)
+	doJSON(r, "POST", "/webhooks", token, `{"url": "`+redirect.URL+`", "event_types": ["task.created"]}`)
+
+	var delivery models.WebhookDelivery
+	json.Unmarshal(doJSON(r, "POST", "/webhooks/2/test", token, "").Body.Bytes(), &delivery)
+	if delivery.StatusCode != http.StatusFound || len(receiver.requests) != 0 {
+		t.Fatalf("Expected the redirect not to be followed, got %+v", delivery)
+	}
+
+	WebhookAllowPrivate = false
+	defer func() { WebhookAllowPrivate = true }()
+	for _, target := range []string{"http://127.0.0.1:6379", "http://169.254.169.254/latest/meta-data", "http://10.0.0.7", "http://[::1]:8080"} {
+		if w := doJSON(r, "POST", "/webhooks", token, `{"url": "`+target+`", "event_types": ["task.created"]}`); w.Code != 400 {
+			t.Fatalf("Expected 400 for %s, got %d", target, w.Code)
+		}
+	}
+
+	// The address is checked again when connecting, e.g. after a DNS change
+	delivery = models.WebhookDelivery{}
+	json.Unmarshal(doJSON(r, "POST", "/webhooks/1/test", token, "").Body.Bytes(), &delivery)
+	if delivery.StatusCode != 0 || !strings.Contains(delivery.Error, errPrivateAddress.Error()) || len(receiver.requests) != 0 {
+		t.Fatalf("Expected the connection to be refused, got %+v", delivery)
+	}
+}
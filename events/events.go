@@ -0,0 +1,163 @@
+// Package events is a small in-process publish/subscribe hub for changes to
+// tasks, projects and comments. Controllers publish events after a change is
+// committed; notifications and other integrations subscribe to them.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TaskCreated      = "task.created"
+	TaskUpdated      = "task.updated"
+	TaskDeleted      = "task.deleted"
+	TaskAssigned     = "task.assigned"
+	TaskDueSoon      = "task.due_soon"
+	TaskReminder     = "task.reminder"
+	CommentCreated   = "comment.created"
+	CommentMentioned = "comment.mentioned"
+	ProjectCreated   = "project.created"
+)
+
+// Types lists every event type, e.g. for validating subscriptions
+var Types = []string{TaskCreated, TaskUpdated, TaskDeleted, TaskAssigned, TaskDueSoon, TaskReminder, CommentCreated, CommentMentioned, ProjectCreated}
+
+// Event describes a single change
+type Event struct {
+	ID        uint64      `json:"id" example:"42"`
+	Type      string      `json:"type" example:"task.updated"`
+	ActorID   uint        `json:"actor_id" example:"2"` // user who made the change
+	ProjectID uint        `json:"project_id,omitempty" example:"1"`
+	TaskID    uint        `json:"task_id,omitempty" example:"7"`
+	Data      interface{} `json:"data"` // the task, project or comment after the change
+	// Recipients addresses the event to specific users (e.g. the ones mentioned
+	// in a comment) instead of everyone following the task
+	Recipients []uint    `json:"-"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-05-07T12:34:56Z"`
+}
+
+// Handler receives published events. Handlers run synchronously in the
+// publisher's goroutine, so slow work should be handed off.
+type Handler func(Event)
+
+// LogSize is how many recent events are kept for clients resuming a stream
+const LogSize = 1000
+
+// ListenerBuffer is how many events a listener may fall behind before it is dropped
+const ListenerBuffer = 64
+
+var (
+	mu        sync.RWMutex
+	handlers  []Handler
+	listeners = make(map[chan Event]struct{})
+	lastID    uint64
+	recent    []Event // ring buffer of the last LogSize events
+)
+
+// Subscribe registers a handler for every future event.
+func Subscribe(h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, h)
+}
+
+// Publish assigns the event an ID and timestamp and passes it to every handler.
+func Publish(e Event) Event {
+	mu.Lock()
+	lastID++
+	e.ID = lastID
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if len(recent) < LogSize {
+		recent = append(recent, e)
+	} else {
+		recent[(e.ID-1)%LogSize] = e
+	}
+	for ch := range listeners {
+		select {
+		case ch <- e:
+		default:
+			// Too slow to keep up; the client can resume from the log
+			delete(listeners, ch)
+			close(ch)
+		}
+	}
+	subscribers := handlers
+	mu.Unlock()
+
+	for _, h := range subscribers {
+		h(e)
+	}
+	return e
+}
+
+// Listen returns a channel receiving every future event, for long-lived
+// consumers such as streaming connections, and a function to stop listening.
+// The channel is closed if the listener falls more than ListenerBuffer events
+// behind.
+func Listen() (<-chan Event, func()) {
+	ch := make(chan Event, ListenerBuffer)
+	mu.Lock()
+	listeners[ch] = struct{}{}
+	mu.Unlock()
+
+	return ch, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := listeners[ch]; ok {
+			delete(listeners, ch)
+			close(ch)
+		}
+	}
+}
+
+// Since returns the logged events published after the one with the given ID,
+// oldest first. ok is false when events after that ID have already dropped
+// out of the log, so the caller missed some.
+func Since(id uint64) (missed []Event, ok bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if id > lastID {
+		// The ID is from before a restart, the log doesn't survive those
+		return nil, false
+	}
+	if id == lastID {
+		return nil, true
+	}
+	oldest := lastID - uint64(len(recent)) + 1
+	if id+1 < oldest {
+		id = oldest - 1
+		ok = false
+	} else {
+		ok = true
+	}
+	for next := id + 1; next <= lastID; next++ {
+		missed = append(missed, recent[(next-1)%LogSize])
+	}
+	return missed, ok
+}
+
+// LastID returns the ID of the most recently published event
+func LastID() uint64 {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lastID
+}
+
+// Reset removes all handlers and listeners and clears the log. It is meant
+// for tests.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = nil
+	for ch := range listeners {
+		close(ch)
+	}
+	listeners = make(map[chan Event]struct{})
+	recent = nil
+	lastID = 0
+}
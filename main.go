@@ -14,10 +14,15 @@ import (
 	"go_task_api/controllers"
 	"go_task_api/middlewares"
 	"go_task_api/models"
+	"go_task_api/utils"
 	_ "go_task_api/docs" 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"time"
+	"flag"
+	"fmt"
+	"os"
 )
 
 var DB *gorm.DB
@@ -28,18 +33,69 @@ func initDatabase() {
 	if err != nil {
 		panic("Failed to connect to database!")
 	}
-	DB.AutoMigrate(&models.User{}, &models.Task{}, &models.Project{}, &models.Tag{},)
+	DB.AutoMigrate(models.All()...)
+}
+
+// createAdmin handles "create-admin -username NAME [-email ADDRESS]", taking
+// the password from -password or the ADMIN_PASSWORD environment variable.
+func createAdmin(args []string) {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	username := flags.String("username", "", "admin username")
+	password := flags.String("password", os.Getenv("ADMIN_PASSWORD"), "admin password (default $ADMIN_PASSWORD)")
+	email := flags.String("email", "", "admin email address")
+	flags.Parse(args)
+
+	if err := controllers.CreateAdmin(DB, *username, *password, *email); err != nil {
+		fmt.Fprintln(os.Stderr, "create-admin:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created admin %s\n", *username)
 }
 
 func main() {
+	if err := utils.LoadJWTKeysFromEnv(); err != nil {
+		panic("Invalid JWT key configuration: " + err.Error())
+	}
 	initDatabase()
 
+	if len(os.Args) > 1 && os.Args[1] == "create-admin" {
+		createAdmin(os.Args[2:])
+		return
+	}
+
 	// Inject DB into controllers
 	controllers.InitAuth(DB)
 	controllers.InitTask(DB)
 	controllers.InitProject(DB)
 	controllers.InitTag(DB)
-	middlewares.InitAdmin(DB)
+	controllers.InitChecklist(DB)
+	controllers.InitCustomField(DB)
+	controllers.InitTemplate(DB)
+	controllers.InitCopy(DB)
+	controllers.InitMilestone(DB)
+	controllers.InitSection(DB)
+	controllers.InitComment(DB)
+	controllers.InitWatch(DB)
+	controllers.InitNotification(DB)
+	controllers.InitReminder(DB)
+	controllers.InitWebhook(DB)
+	controllers.InitStream(DB)
+	controllers.InitSocket(DB)
+	// Email is off unless SMTP_HOST is set; MAIL_SENDER=log prints emails,
+	// reset links included, to the log during development
+	if os.Getenv("MAIL_SENDER") == "log" {
+		controllers.InitEmail(DB, utils.LogMailer{})
+	} else if mailer := utils.SMTPMailerFromEnv(); mailer != nil {
+		controllers.InitEmail(DB, mailer)
+	} else {
+		controllers.InitEmail(DB, nil)
+	}
+	middlewares.InitRevocation(DB)
+
+	controllers.StartNotificationPruning(time.Hour)
+	controllers.StartEmailJobs(5 * time.Minute)
+	controllers.StartReminderScheduler(time.Minute)
+	controllers.StartWebhookWorker(30 * time.Second)
 
 	r := gin.Default()
 
@@ -61,6 +117,13 @@ func main() {
 	// @Success 200 {object} map[string]string
 	// @Router /login [post]
 	r.POST("/login", controllers.Login)
+	r.POST("/token/refresh", controllers.RefreshToken)
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
+	r.POST("/password/forgot", controllers.ForgotPassword)
+	r.POST("/password/reset", controllers.ResetPassword)
+	r.POST("/email/verify", controllers.VerifyEmail)
+	// Authenticates itself, browsers can't send an Authorization header with a WebSocket
+	r.GET("/ws", controllers.BoardSocket)
 
 	//swagger routes
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("http://localhost:8080/swagger/doc.json")))
@@ -75,21 +138,96 @@ func main() {
 	{
 		admin.GET("/users", controllers.AdminGetUsers)
 		admin.PUT("/users/:id/role", controllers.AdminUpdateUserRole)
+		admin.POST("/users/:id/revoke-sessions", controllers.AdminRevokeSessions)
+		// Tags are shared by everyone's tasks
+		admin.PUT("/tags/:id", controllers.RenameTag)
+		admin.POST("/tags/:id/merge", controllers.MergeTags)
 	}
 
 	// Protected task routes
 	auth := r.Group("/")
 	auth.Use(middlewares.AuthMiddleware())
 	{
+		auth.POST("/logout", controllers.Logout)
+		auth.POST("/logout-all", controllers.LogoutAll)
+		auth.GET("/me/sessions", controllers.GetSessions)
+		auth.DELETE("/me/sessions/:id", controllers.DeleteSession)
+
+		auth.POST("/invitations", controllers.CreateInvitation)
+		auth.GET("/invitations", controllers.GetInvitations)
+		auth.DELETE("/invitations/:id", controllers.RevokeInvitation)
+
 		auth.GET("/tasks", controllers.GetTasks)
 		auth.POST("/tasks", controllers.CreateTask)
+		auth.GET("/tasks/:id", controllers.GetTask)
 		auth.PUT("/tasks/:id", controllers.UpdateTask)
 		auth.DELETE("/tasks/:id", controllers.DeleteTask)
+		auth.POST("/tasks/:id/move", controllers.MoveTask)
+		auth.POST("/tasks/:id/duplicate", controllers.DuplicateTask)
+
+		auth.GET("/tasks/:id/checklist", controllers.GetChecklist)
+		auth.POST("/tasks/:id/checklist", controllers.AddChecklistItem)
+		auth.PUT("/tasks/:id/checklist", controllers.ReorderChecklist)
+		auth.POST("/tasks/:id/checklist/:item_id/toggle", controllers.ToggleChecklistItem)
+		auth.DELETE("/tasks/:id/checklist/:item_id", controllers.DeleteChecklistItem)
 
 		auth.POST("/projects", controllers.CreateProject)
 		auth.GET("/projects", controllers.GetProjects)
 		auth.GET("/projects/:id/tasks", controllers.GetProjectTasks)
-		
+		auth.GET("/projects/:id/board", controllers.GetProjectBoard)
+		auth.GET("/projects/:id/members", controllers.GetProjectMembers)
+		auth.DELETE("/projects/:id/members/:user_id", controllers.RemoveProjectMember)
+		auth.GET("/projects/:id/fields", controllers.GetCustomFields)
+		auth.POST("/projects/:id/fields", controllers.CreateCustomField)
+		auth.DELETE("/projects/:id/fields/:field_id", controllers.DeleteCustomField)
+		auth.POST("/projects/:id/clone", controllers.CloneProject)
+		auth.POST("/projects/:id/template", controllers.SaveProjectAsTemplate)
+		auth.POST("/projects/from-template/:id", controllers.InstantiateTemplate)
+		auth.GET("/templates", controllers.GetTemplates)
+		auth.POST("/tasks/:id/template", controllers.SaveTaskAsTemplate)
+		auth.POST("/tasks/from-template/:id", controllers.InstantiateTaskTemplate)
+
+		auth.GET("/projects/:id/milestones", controllers.GetMilestones)
+		auth.POST("/projects/:id/milestones", controllers.CreateMilestone)
+		auth.GET("/milestones/:id", controllers.GetMilestone)
+		auth.POST("/milestones/:id/close", controllers.CloseMilestone)
+
+		auth.GET("/projects/:id/sections", controllers.GetSections)
+		auth.POST("/projects/:id/sections", controllers.CreateSection)
+		auth.PUT("/projects/:id/sections", controllers.ReorderSections)
+		auth.PUT("/sections/:id", controllers.UpdateSection)
+		auth.DELETE("/sections/:id", controllers.DeleteSection)
+
+		auth.GET("/tasks/:id/comments", controllers.GetComments)
+		auth.POST("/tasks/:id/comments", controllers.CreateComment)
+
+		auth.POST("/tasks/:id/watch", controllers.WatchTask)
+		auth.DELETE("/tasks/:id/watch", controllers.UnwatchTask)
+		auth.POST("/projects/:id/watch", controllers.WatchProject)
+		auth.DELETE("/projects/:id/watch", controllers.UnwatchProject)
+		auth.GET("/me/watching", controllers.GetWatching)
+
+		auth.GET("/notifications", controllers.GetNotifications)
+		auth.POST("/notifications/:id/read", controllers.MarkNotificationRead)
+		auth.POST("/notifications/read-all", controllers.MarkAllNotificationsRead)
+		auth.GET("/me/notification-preferences", controllers.GetNotificationPreferences)
+		auth.PUT("/me/notification-preferences", controllers.UpdateNotificationPreferences)
+		auth.GET("/me/email-settings", controllers.GetEmailSettings)
+		auth.PUT("/me/email-settings", controllers.UpdateEmailSettings)
+
+		auth.GET("/tasks/:id/reminders", controllers.GetTaskReminders)
+		auth.POST("/tasks/:id/reminders", controllers.CreateReminder)
+		auth.DELETE("/reminders/:id", controllers.DeleteReminder)
+		auth.GET("/reminders", controllers.GetPendingReminders)
+
+		auth.POST("/webhooks", controllers.CreateWebhook)
+		auth.GET("/webhooks", controllers.GetWebhooks)
+		auth.DELETE("/webhooks/:id", controllers.DeleteWebhook)
+		auth.GET("/webhooks/:id/deliveries", controllers.GetWebhookDeliveries)
+		auth.POST("/webhooks/:id/test", controllers.TestWebhook)
+
+		auth.GET("/events", controllers.StreamEvents)
+		auth.POST("/ws/ticket", controllers.CreateSocketTicket)
 
 	}
 
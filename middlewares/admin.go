@@ -1,25 +1,16 @@
 package middlewares
 
 import (
-	"go_task_api/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-var AdminDB *gorm.DB
-
-func InitAdmin(db *gorm.DB) {
-	AdminDB = db
-}
-
+// AdminOnly lets through requests whose access token carries the admin role.
+// It must run after AuthMiddleware.
 func AdminOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID := c.MustGet("userID").(uint)
-
-		var user models.User
-		if err := AdminDB.First(&user, userID).Error; err != nil || user.Role != "admin" {
+		if c.GetString("role") != "admin" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access only"})
 			c.Abort()
 			return
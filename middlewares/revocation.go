@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"go_task_api/models"
+	"go_task_api/utils"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var RevocationDB *gorm.DB
+
+// SessionTouchInterval is how stale a session's last-used time may get
+// before a request writes a new one
+var SessionTouchInterval = 5 * time.Minute
+
+var (
+	touchMu   sync.Mutex
+	touchedAt = make(map[string]time.Time) // session ID -> last write
+)
+
+// InitRevocation enables the revocation checks in AuthMiddleware
+func InitRevocation(db *gorm.DB) {
+	RevocationDB = db
+}
+
+// isRevoked reports whether the token was logged out, its session was ended,
+// or it was issued before all of the user's sessions were revoked.
+func isRevoked(claims *utils.Claims) bool {
+	if RevocationDB == nil {
+		return false
+	}
+
+	var user models.User
+	if err := RevocationDB.Select("token_version").First(&user, claims.UserID).Error; err != nil {
+		return true
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return true
+	}
+
+	if claims.SessionID != "" {
+		var session models.Session
+		if err := RevocationDB.Select("revoked_at").Where("family_id = ?", claims.SessionID).First(&session).Error; err != nil || session.RevokedAt != nil {
+			return true
+		}
+	}
+
+	if claims.ID == "" {
+		return false
+	}
+	var count int64
+	RevocationDB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&count)
+	return count > 0
+}
+
+// StillValid reports whether a token accepted earlier has neither expired nor
+// been revoked since, for connections that outlive the request that opened them.
+func StillValid(claims *utils.Claims) bool {
+	if claims.ExpiresAt != nil && time.Now().After(claims.ExpiresAt.Time) {
+		return false
+	}
+	return !isRevoked(claims)
+}
+
+// touchSession records that a session was used, writing to the database at
+// most once per SessionTouchInterval per session.
+func touchSession(sessionID string) {
+	if RevocationDB == nil || sessionID == "" {
+		return
+	}
+
+	now := time.Now()
+	touchMu.Lock()
+	if now.Sub(touchedAt[sessionID]) < SessionTouchInterval {
+		touchMu.Unlock()
+		return
+	}
+	touchedAt[sessionID] = now
+	// Forget sessions that haven't been seen for a while so the map stays small
+	for id, at := range touchedAt {
+		if now.Sub(at) > 2*SessionTouchInterval {
+			delete(touchedAt, id)
+		}
+	}
+	touchMu.Unlock()
+
+	RevocationDB.Model(&models.Session{}).
+		Where("family_id = ? AND last_used_at < ?", sessionID, now.Add(-SessionTouchInterval)).
+		Update("last_used_at", now)
+}
@@ -4,7 +4,9 @@ package models
 type RegisterRequest struct {
 	Username string `json:"username" example:"sumit"`
 	Password string `json:"password" example:"password123"`
-	Role     string `json:"role" example:"admin"` // optional, defaults to "user"
+	Email    string `json:"email" example:"sumit@example.com"` // optional, needed for email notifications
+	// InviteToken accepts an invitation, required when registration is invite-only
+	InviteToken string `json:"invite_token,omitempty" example:"3f9a0c..."`
 }
 
 // LoginRequest represents the payload for user login
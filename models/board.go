@@ -0,0 +1,27 @@
+package models
+
+// BoardStatuses are the default board columns, in display order. Tasks with
+// any other status get a column of their own after these.
+var BoardStatuses = []string{"todo", "in-progress", "done"}
+
+// PositionGap is the spacing between task positions after a rebalance, which
+// leaves room for many moves before ranks run out of precision again.
+const PositionGap = 1024.0
+
+// Board is a project's tasks grouped into status columns
+type Board struct {
+	ProjectID uint          `json:"project_id" example:"1"`
+	Columns   []BoardColumn `json:"columns"`
+}
+
+// BoardColumn holds the tasks of one status, in position order
+type BoardColumn struct {
+	Status string `json:"status" example:"todo"`
+	Tasks  []Task `json:"tasks"`
+}
+
+// MoveTaskRequest represents the payload for moving a task on a board
+type MoveTaskRequest struct {
+	Status string `json:"status" example:"done"`
+	Index  int    `json:"index" example:"0"` // 0-based position within the target column
+}
@@ -0,0 +1,26 @@
+package models
+
+// ChecklistItem is a single, ordered entry in a task's checklist
+type ChecklistItem struct {
+	ID       uint   `json:"id" example:"1"`
+	TaskID   uint   `json:"task_id" gorm:"index" example:"1"`
+	Text     string `json:"text" example:"Check the fridge"`
+	Checked  bool   `json:"checked" example:"false"`
+	Position int    `json:"position" example:"0"`
+}
+
+// ChecklistProgress summarises how many checklist items of a task are checked
+type ChecklistProgress struct {
+	Done  int `json:"done" example:"3"`
+	Total int `json:"total" example:"5"`
+}
+
+// ChecklistItemRequest represents the payload for adding a checklist item
+type ChecklistItemRequest struct {
+	Text string `json:"text" example:"Check the fridge"`
+}
+
+// ReorderChecklistRequest lists every checklist item ID of a task in the new order
+type ReorderChecklistRequest struct {
+	ItemIDs []uint `json:"item_ids" example:"3,1,2"`
+}
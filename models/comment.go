@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Comment is a message left on a task
+type Comment struct {
+	ID        uint      `json:"id" example:"1"`
+	TaskID    uint      `json:"task_id" gorm:"index" example:"1"`
+	UserID    uint      `json:"user_id" example:"2"`
+	Body      string    `json:"body" example:"Done, see the attached receipt"`
+	CreatedAt time.Time `json:"created_at" example:"2025-05-07T12:34:56Z"`
+}
+
+// CommentRequest represents the payload for adding a comment
+type CommentRequest struct {
+	Body string `json:"body" example:"Done, see the attached receipt"`
+}
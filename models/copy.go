@@ -0,0 +1,38 @@
+package models
+
+// CopyOptions controls what comes along when a project or task is copied.
+// Everything is copied unless switched off.
+type CopyOptions struct {
+	Tags         bool `json:"tags" example:"true"`
+	Subtasks     bool `json:"subtasks" example:"true"`
+	Checklists   bool `json:"checklists" example:"true"`
+	CustomFields bool `json:"custom_fields" example:"true"`
+	// Comments keep their author and time
+	Comments bool `json:"comments" example:"true"`
+	// Attachments can't be copied as tasks have none yet; asking for them is an error
+	Attachments bool `json:"attachments" example:"false"`
+}
+
+// DefaultCopyOptions copies everything
+func DefaultCopyOptions() CopyOptions {
+	return CopyOptions{Tags: true, Subtasks: true, Checklists: true, CustomFields: true, Comments: true}
+}
+
+// CloneProjectRequest represents the payload for cloning a project
+type CloneProjectRequest struct {
+	Name string `json:"name" example:"Work (copy)"`
+	CopyOptions
+}
+
+// DuplicateTaskRequest represents the payload for duplicating a task
+type DuplicateTaskRequest struct {
+	Title string `json:"title" example:"Buy milk (copy)"`
+	CopyOptions
+}
+
+// CopyResult holds the IDs created by a clone or duplicate
+type CopyResult struct {
+	ProjectID uint          `json:"project_id,omitempty" example:"3"`
+	TaskID    uint          `json:"task_id,omitempty" example:"12"`
+	TaskIDs   map[uint]uint `json:"task_ids"` // original task ID -> new task ID
+}
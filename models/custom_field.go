@@ -0,0 +1,29 @@
+package models
+
+// Custom field types
+const (
+	FieldTypeText   = "text"
+	FieldTypeNumber = "number"
+	FieldTypeDate   = "date" // YYYY-MM-DD
+	FieldTypeEnum   = "enum"
+	FieldTypeUser   = "user" // user ID
+)
+
+// CustomField is a typed, per-project field whose values are stored on tasks
+type CustomField struct {
+	ID        uint     `json:"id" example:"1"`
+	ProjectID uint     `json:"project_id" gorm:"index" example:"1"`
+	Name      string   `json:"name" example:"severity"`
+	Type      string   `json:"type" example:"enum"`
+	Options   []string `json:"options,omitempty" gorm:"serializer:json" example:"low,medium,high"` // allowed values for enum fields
+	Required  bool     `json:"required" example:"false"`
+}
+
+// CustomFieldValue is the value of a custom field on one task, stored in a
+// canonical text form so it can be filtered and sorted in SQL
+type CustomFieldValue struct {
+	ID      uint   `json:"id" example:"1"`
+	TaskID  uint   `json:"task_id" gorm:"uniqueIndex:idx_task_field" example:"1"`
+	FieldID uint   `json:"field_id" gorm:"uniqueIndex:idx_task_field;index" example:"1"`
+	Value   string `json:"value" example:"high"`
+}
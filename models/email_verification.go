@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// EmailVerification is a single-use token proving that a user receives mail
+// at Email. Only the hash of the token is stored.
+type EmailVerification struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Email     string    `json:"email"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VerifyEmailRequest represents the payload for confirming an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" example:"8c41d7..."`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Invitation lets someone sign up while registration is invite-only. The
+// token is single use and only its hash is stored. ProjectIDs are projects
+// the new user becomes a member of.
+type Invitation struct {
+	ID         uint       `json:"id" example:"1"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	Email      string     `json:"email,omitempty" example:"new.hire@example.com"`
+	Role       string     `json:"role" example:"user"`
+	ProjectIDs []uint     `json:"project_ids" gorm:"serializer:json" example:"1,2"`
+	InvitedBy  uint       `json:"invited_by" gorm:"index" example:"1"`
+	ExpiresAt  time.Time  `json:"expires_at" example:"2025-05-14T12:34:56Z"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" example:"2025-05-08T09:00:00Z"`
+	AcceptedBy *uint      `json:"accepted_by,omitempty" example:"7"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" example:"2025-05-07T12:34:56Z"`
+
+	Status string `json:"status" gorm:"-" example:"pending"` // pending, accepted, revoked or expired
+	Token  string `json:"token,omitempty" gorm:"-"`          // only returned when the invitation is created
+}
+
+// InvitationRequest represents the payload for inviting someone
+type InvitationRequest struct {
+	Email      string `json:"email" example:"new.hire@example.com"`
+	Role       string `json:"role" example:"user"` // admins only may invite admins
+	ProjectIDs []uint `json:"project_ids" example:"1,2"`
+	ExpiresIn  int    `json:"expires_in_hours" example:"72"` // default 168 (a week)
+}
+
+// SetStatus fills in Status as of now
+func (i *Invitation) SetStatus(now time.Time) {
+	switch {
+	case i.AcceptedAt != nil:
+		i.Status = "accepted"
+	case i.RevokedAt != nil:
+		i.Status = "revoked"
+	case now.After(i.ExpiresAt):
+		i.Status = "expired"
+	default:
+		i.Status = "pending"
+	}
+}
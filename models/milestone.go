@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Milestone is a time-boxed goal (e.g. a two-week sprint) within a project
+type Milestone struct {
+	ID        uint       `json:"id" example:"1"`
+	ProjectID uint       `json:"project_id" gorm:"index" example:"1"`
+	Name      string     `json:"name" example:"Sprint 12"`
+	StartDate time.Time  `json:"start_date" example:"2025-05-05T00:00:00Z"`
+	EndDate   time.Time  `json:"end_date" example:"2025-05-16T00:00:00Z"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty" example:"2025-05-16T17:00:00Z"`
+}
+
+// MilestoneScopeChange records a task being added to (+1) or removed from (-1)
+// a milestone, so scope creep can be charted over time
+type MilestoneScopeChange struct {
+	ID          uint      `json:"id" example:"1"`
+	MilestoneID uint      `json:"milestone_id" gorm:"index" example:"1"`
+	TaskID      uint      `json:"task_id" example:"1"`
+	Delta       int       `json:"delta" example:"1"`
+	CreatedAt   time.Time `json:"created_at" example:"2025-05-06T10:00:00Z"`
+}
+
+// MilestoneReport is a milestone with its progress, scope history and remaining work
+type MilestoneReport struct {
+	Milestone
+	Done            int              `json:"done" example:"7"`
+	Total           int              `json:"total" example:"10"`
+	Percent         float64          `json:"percent" example:"70"`
+	ScopeChanges    []ScopeChangeDay `json:"scope_changes"`
+	UnfinishedTasks []Task           `json:"unfinished_tasks"`
+}
+
+// ScopeChangeDay summarises the scope changes of a milestone on one day
+type ScopeChangeDay struct {
+	Date    string `json:"date" example:"2025-05-06"`
+	Added   int    `json:"added" example:"3"`
+	Removed int    `json:"removed" example:"1"`
+	Scope   int    `json:"scope" example:"12"` // tasks in the milestone at the end of the day
+}
+
+// CloseMilestoneRequest optionally names the milestone unfinished tasks roll over to
+type CloseMilestoneRequest struct {
+	NextMilestoneID *uint `json:"next_milestone_id" example:"2"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// All lists every model that has a table, in migration order
+func All() []interface{} {
+	return []interface{}{
+		&User{}, &Task{}, &Project{}, &Tag{}, &ChecklistItem{},
+		&CustomField{}, &CustomFieldValue{}, &ProjectTemplate{}, &TemplateTask{},
+		&Milestone{}, &MilestoneScopeChange{}, &Section{},
+		&Comment{}, &Watch{}, &Notification{}, &NotificationPreference{},
+		&Reminder{}, &Webhook{}, &WebhookDelivery{}, &RefreshToken{},
+		&RevokedToken{}, &Session{}, &Invitation{}, &PasswordReset{},
+		&EmailVerification{}, &ProjectMember{},
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Notification is an entry in a user's in-app inbox
+type Notification struct {
+	ID        uint       `json:"id" example:"1"`
+	UserID    uint       `json:"user_id" gorm:"index" example:"2"`
+	Type      string     `json:"type" example:"task.updated"`
+	ActorID   uint       `json:"actor_id" example:"3"`
+	ProjectID uint       `json:"project_id,omitempty" example:"1"`
+	TaskID    uint       `json:"task_id,omitempty" example:"7"`
+	Message   string     `json:"message" example:"alex updated \"Buy milk\""`
+	ReadAt    *time.Time `json:"read_at,omitempty" example:"2025-05-07T13:00:00Z"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index" example:"2025-05-07T12:34:56Z"`
+}
+
+// NotificationPreference turns in-app and email notifications for one event
+// type on or off. Event types without a stored preference use the defaults:
+// always in-app, and email only for assignments, mentions and due dates.
+type NotificationPreference struct {
+	ID        uint   `json:"-"`
+	UserID    uint   `json:"-" gorm:"uniqueIndex:idx_user_event"`
+	EventType string `json:"event_type" gorm:"uniqueIndex:idx_user_event" example:"task.updated"`
+	InApp     bool   `json:"in_app" example:"true"`
+	Email     bool   `json:"email" example:"false"`
+}
+
+// NotificationPreferenceRequest changes the channels for one event type;
+// channels left out are not changed
+type NotificationPreferenceRequest struct {
+	EventType string `json:"event_type" example:"task.updated"`
+	InApp     *bool  `json:"in_app,omitempty" example:"true"`
+	Email     *bool  `json:"email,omitempty" example:"false"`
+}
+
+// NotificationList is a page of notifications
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int64          `json:"unread" example:"3"`
+}
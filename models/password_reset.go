@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// PasswordReset is a single-use token for setting a new password. Only the
+// hash of the token is stored.
+type PasswordReset struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ForgotPasswordRequest represents the payload for requesting a reset; give
+// the username or the email address of the account
+type ForgotPasswordRequest struct {
+	Username string `json:"username" example:"sumit"`
+	Email    string `json:"email" example:"sumit@example.com"`
+}
+
+// ResetPasswordRequest represents the payload for setting a new password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" example:"3f9a0c..."`
+	Password string `json:"password" example:"n3w-passw0rd"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ProjectMember gives a user who doesn't own a project access to it: members
+// see the project and its tasks and can comment on and follow them, while
+// editing stays with the owner.
+type ProjectMember struct {
+	ID        uint      `json:"id" example:"1"`
+	ProjectID uint      `json:"project_id" gorm:"uniqueIndex:idx_project_member" example:"1"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_project_member;index" example:"7"`
+	Username  string    `json:"username" gorm:"-" example:"new.hire"`
+	CreatedAt time.Time `json:"created_at" example:"2025-05-08T09:00:00Z"`
+}
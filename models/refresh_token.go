@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived token that can be traded for a new access
+// token once. Every refresh rotates it; the tokens descending from one login
+// share a FamilyID so the whole chain can be revoked when a used token is
+// presented again. Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	FamilyID  string     `json:"family_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`    // set once it was exchanged
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // set when its family was revoked
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RefreshRequest represents the payload for refreshing an access token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" example:"3f9a0c..."`
+}
+
+// TokenResponse is returned by login and token refresh. All fields are
+// strings, as the login response always has been.
+type TokenResponse struct {
+	Token        string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR..."`
+	RefreshToken string `json:"refresh_token" example:"3f9a0c..."`
+	ExpiresAt    string `json:"expires_at" example:"2025-05-07T12:49:56Z"` // when the access token expires
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Reminder nudges a user about a task, either at a fixed time (RemindAt) or
+// a number of minutes before the task's due date (MinutesBefore). FireAt is
+// the resulting moment, nil while a relative reminder's task has no due date.
+type Reminder struct {
+	ID            uint       `json:"id" example:"1"`
+	TaskID        uint       `json:"task_id" gorm:"index" example:"1"`
+	UserID        uint       `json:"user_id" gorm:"index" example:"2"`
+	RemindAt      *time.Time `json:"remind_at,omitempty" example:"2025-05-10T09:00:00Z"`
+	MinutesBefore *int       `json:"minutes_before,omitempty" example:"60"`
+	FireAt        *time.Time `json:"fire_at" gorm:"index" example:"2025-05-10T09:00:00Z"`
+	FiredAt       *time.Time `json:"fired_at,omitempty" example:"2025-05-10T09:00:03Z"`
+	CreatedAt     time.Time  `json:"created_at" example:"2025-05-07T12:34:56Z"`
+}
+
+// ReminderRequest represents the payload for adding a reminder; set exactly
+// one of RemindAt and MinutesBefore
+type ReminderRequest struct {
+	RemindAt      *time.Time `json:"remind_at" example:"2025-05-10T09:00:00Z"`
+	MinutesBefore *int       `json:"minutes_before" example:"60"`
+}
+
+// FireTime works out when the reminder should fire for a task due at dueDate
+func (r *Reminder) FireTime(dueDate *time.Time) *time.Time {
+	if r.RemindAt != nil {
+		return r.RemindAt
+	}
+	if r.MinutesBefore == nil || dueDate == nil {
+		return nil
+	}
+	at := dueDate.Add(-time.Duration(*r.MinutesBefore) * time.Minute)
+	return &at
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RevokedToken is an access token that was logged out before it expired. It
+// only needs to be kept until ExpiresAt, after which the token is refused anyway.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// Section is a named, ordered group of tasks within a project (e.g. "This week"),
+// independent of the task's status
+type Section struct {
+	ID        uint   `json:"id" example:"1"`
+	ProjectID uint   `json:"project_id" gorm:"index" example:"1"`
+	Name      string `json:"name" example:"Waiting on others"`
+	Position  int    `json:"position" example:"0"`
+}
+
+// SectionRequest represents the payload for creating or renaming a section
+type SectionRequest struct {
+	Name string `json:"name" example:"This week"`
+}
+
+// ReorderSectionsRequest lists every section ID of a project in the new order
+type ReorderSectionsRequest struct {
+	SectionIDs []uint `json:"section_ids" example:"2,1,3"`
+}
+
+// SectionGroup holds the tasks of one section. Section is null for tasks
+// that aren't in any section.
+type SectionGroup struct {
+	Section *Section `json:"section"`
+	Tasks   []Task   `json:"tasks"`
+}
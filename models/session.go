@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is one login on one device. Its FamilyID is shared by the refresh
+// tokens of the login and carried as "sid" in its access tokens.
+type Session struct {
+	ID         uint       `json:"id" example:"1"`
+	UserID     uint       `json:"user_id" gorm:"index" example:"2"`
+	FamilyID   string     `json:"-" gorm:"uniqueIndex"`
+	UserAgent  string     `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64) Firefox/126.0"`
+	IP         string     `json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time  `json:"created_at" example:"2025-05-07T12:34:56Z"`
+	LastUsedAt time.Time  `json:"last_used_at" example:"2025-05-07T14:02:11Z"` // updated at most every few minutes
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current" gorm:"-"` // the session making the request
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SocketRequest is a message sent by a WebSocket client. Type is one of
+// subscribe, unsubscribe, move, update and ping; ID is echoed back in the
+// reply so the client can match them up.
+type SocketRequest struct {
+	Type      string           `json:"type" example:"move"`
+	ID        string           `json:"id,omitempty" example:"r1"`
+	ProjectID uint             `json:"project_id,omitempty" example:"1"` // subscribe, unsubscribe
+	TaskID    uint             `json:"task_id,omitempty" example:"7"`    // move, update
+	Move      *MoveTaskRequest `json:"move,omitempty"`
+	Task      json.RawMessage  `json:"task,omitempty" swaggertype:"object"` // same body as PUT /tasks/{id}
+}
+
+// SocketMessage is a message sent to a WebSocket client: a reply to a
+// request, a change event, or the presence list of a project.
+type SocketMessage struct {
+	Type      string      `json:"type" example:"reply"` // reply, event, presence or closed
+	ID        string      `json:"id,omitempty" example:"r1"`
+	Status    int         `json:"status,omitempty" example:"200"`
+	Error     string      `json:"error,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	ProjectID uint        `json:"project_id,omitempty" example:"1"`
+	Users     []Presence  `json:"users,omitempty"`
+}
+
+// Presence is a user currently viewing a project
+type Presence struct {
+	UserID   uint   `json:"user_id" example:"2"`
+	Username string `json:"username" example:"alex"`
+}
+
+// SocketTicket lets a browser, which can't send an Authorization header with
+// a WebSocket, open GET /ws?ticket=... once before it expires
+type SocketTicket struct {
+	Ticket    string    `json:"ticket" example:"5f2b1c0e9a..."`
+	ExpiresAt time.Time `json:"expires_at" example:"2025-05-07T12:35:26Z"`
+}
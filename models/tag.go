@@ -1,6 +1,50 @@
 package models
 
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ScopeSeparator splits a scoped label like "area::backend" into its scope and value
+const ScopeSeparator = "::"
+
 type Tag struct {
-	ID   uint   `json:"id" example:"1"`
+	ID       uint   `json:"id" example:"1"`
+	Name     string `json:"name" example:"area::backend"`
+	ParentID *uint  `json:"parent_id,omitempty" example:"3"`
+	Scope    string `json:"scope,omitempty" gorm:"index" example:"area"` // derived from Name, read-only
+}
+
+// BeforeSave keeps Scope in sync with the tag name.
+func (t *Tag) BeforeSave(tx *gorm.DB) error {
+	t.Scope = ScopeOf(t.Name)
+	return nil
+}
+
+// ScopeOf returns the scope of a label such as "area::backend" ("area"),
+// or an empty string for unscoped labels.
+func ScopeOf(name string) string {
+	i := strings.LastIndex(name, ScopeSeparator)
+	if i <= 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+// TagWithCounts is a tag together with how many open and closed tasks use it
+type TagWithCounts struct {
+	Tag
+	OpenCount   int64 `json:"open_count" example:"4"`
+	ClosedCount int64 `json:"closed_count" example:"12"`
+}
+
+// RenameTagRequest represents the payload for renaming a tag
+type RenameTagRequest struct {
 	Name string `json:"name" example:"urgent"`
-}
\ No newline at end of file
+}
+
+// MergeTagsRequest represents the payload for merging tags into a target tag
+type MergeTagsRequest struct {
+	SourceIDs []uint `json:"source_ids" example:"2,3"`
+}
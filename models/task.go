@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Task struct {
 	ID        uint   `json:"id" example:"1"`
 	Title     string `json:"title" example:"Buy milk"`
@@ -8,5 +10,39 @@ type Task struct {
 	CreatedAt string `json:"created_at" example:"2025-05-07T12:34:56Z"`
 	UpdatedAt string `json:"updated_at" example:"2025-05-07T13:34:56Z"`
 	ProjectID uint `json:"project_id" example:"1"`
-	Tags      []Tag  `json:"tags"`
+	Tags      []Tag  `json:"tags" gorm:"many2many:task_tags;"`
+	Position  float64 `json:"position" example:"1024"` // rank within the task's board column
+
+	// ParentID makes this task a subtask of another task
+	ParentID *uint      `json:"parent_id,omitempty" gorm:"index" example:"1"`
+	DueDate  *time.Time `json:"due_date,omitempty" example:"2025-05-10T17:00:00Z"`
+	// DueSoonSentAt records when the due-soon notice went out, so it is sent once per due date
+	DueSoonSentAt *time.Time `json:"-"`
+
+	MilestoneID *uint `json:"milestone_id,omitempty" gorm:"index" example:"1"`
+	SectionID   *uint `json:"section_id,omitempty" gorm:"index" example:"1"`
+	AssigneeID  *uint `json:"assignee_id,omitempty" gorm:"index" example:"3"`
+
+	// Description is Markdown source; DescriptionHTML is only filled in for ?render=html
+	Description     string `json:"description" example:"Semi-skimmed, see #12"`
+	DescriptionHTML string `json:"description_html,omitempty" gorm:"-"`
+
+	Checklist         []ChecklistItem    `json:"checklist,omitempty"`
+	ChecklistProgress *ChecklistProgress `json:"checklist_progress,omitempty" gorm:"-"`
+
+	// CustomFields maps the project's custom field names to this task's values
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty" gorm:"-"`
+}
+
+// ClosedStatuses lists the task statuses that count as finished work.
+var ClosedStatuses = []string{"done", "completed", "closed"}
+
+// IsClosed reports whether the task is in one of the ClosedStatuses.
+func (t *Task) IsClosed() bool {
+	for _, s := range ClosedStatuses {
+		if t.Status == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Template kinds
+const (
+	TemplateKindProject = "project"
+	TemplateKindTask    = "task"
+)
+
+// ProjectTemplate captures a project's tasks so it can be set up again from
+// scratch. Task templates (Kind "task") hold a single task and its subtasks,
+// to be added to an existing project.
+type ProjectTemplate struct {
+	ID     uint           `json:"id" example:"1"`
+	UserID uint           `json:"user_id" gorm:"index" example:"2"`
+	Kind   string         `json:"kind" gorm:"default:project" example:"project"`
+	Name   string         `json:"name" example:"New hire onboarding"`
+	Tasks  []TemplateTask `json:"tasks" gorm:"foreignKey:TemplateID"`
+}
+
+// TemplateTask is a task blueprint inside a template. Due dates are kept as an
+// offset in days from the start date chosen when the template is instantiated.
+type TemplateTask struct {
+	ID            uint    `json:"id" example:"1"`
+	TemplateID    uint    `json:"template_id" gorm:"index" example:"1"`
+	ParentID      *uint   `json:"parent_id,omitempty" example:"1"` // parent TemplateTask, for subtasks
+	Title         string  `json:"title" example:"Set up laptop"`
+	Description   string  `json:"description" example:"Ask IT for a **loaner** first"`
+	Status        string  `json:"status" example:"todo"`
+	Position      float64 `json:"position" example:"1024"`
+	DueOffsetDays *int    `json:"due_offset_days,omitempty" example:"3"`
+	TagIDs        []uint  `json:"tag_ids" gorm:"serializer:json" example:"1,2"`
+}
+
+// SaveTemplateRequest represents the payload for saving a project or task as a template
+type SaveTemplateRequest struct {
+	Name string `json:"name" example:"New hire onboarding"`
+	// StartDate anchors the due offsets; defaults to the earliest due date among the saved tasks
+	StartDate *time.Time `json:"start_date" example:"2025-05-05T09:00:00Z"`
+}
+
+// InstantiateTemplateRequest represents the payload for creating a project from a template
+type InstantiateTemplateRequest struct {
+	Name      string    `json:"name" example:"Onboarding: Alex"`
+	StartDate time.Time `json:"start_date" example:"2025-06-02T09:00:00Z"`
+}
+
+// InstantiateTaskTemplateRequest represents the payload for creating a task from a task template
+type InstantiateTaskTemplateRequest struct {
+	ProjectID uint      `json:"project_id" example:"1"`
+	StartDate time.Time `json:"start_date" example:"2025-06-02T09:00:00Z"`
+}
+
+// InstantiateTemplateResponse is returned after creating a project from a template
+type InstantiateTemplateResponse struct {
+	Project Project `json:"project"`
+	Tasks   []Task  `json:"tasks"`
+}
@@ -2,6 +2,8 @@
 package models
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	// "gorm.io/gorm"
 )
@@ -13,6 +15,23 @@ type User struct {
 	Role      string `json:"role" example:"admin"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+
+	Email        string     `json:"email,omitempty" example:"sumit@example.com"`
+	DailyDigest  bool       `json:"daily_digest" example:"false"` // opt-in daily summary email
+	LastDigestAt *time.Time `json:"-"`
+	// EmailVerifiedAt is set once the user confirms Email, and cleared when it changes
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// TokenVersion is bumped to invalidate every access token issued so far
+	TokenVersion int `json:"-"`
+}
+
+// EmailSettings represents a user's email address and digest subscription.
+// Verified is read-only; a new address is confirmed through POST /email/verify.
+type EmailSettings struct {
+	Email       string `json:"email" example:"sumit@example.com"`
+	DailyDigest bool   `json:"daily_digest" example:"true"`
+	Verified    bool   `json:"verified" example:"false"`
 }
 
 func (u *User) SetPassword(password string) error {
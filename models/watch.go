@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Watch subscribes a user to changes of a task or of a whole project.
+// Exactly one of TaskID and ProjectID is set, the other is 0.
+type Watch struct {
+	ID        uint      `json:"id" example:"1"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_watch" example:"2"`
+	TaskID    uint      `json:"task_id,omitempty" gorm:"uniqueIndex:idx_watch;index" example:"1"`
+	ProjectID uint      `json:"project_id,omitempty" gorm:"uniqueIndex:idx_watch;index" example:"0"`
+	CreatedAt time.Time `json:"created_at" example:"2025-05-07T12:34:56Z"`
+}
+
+// Watching lists everything a user follows
+type Watching struct {
+	Tasks    []Task    `json:"tasks"`
+	Projects []Project `json:"projects"`
+}
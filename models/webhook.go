@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Webhook sends signed JSON payloads for the chosen event types to a URL.
+// With a ProjectID it covers that project, otherwise every task and project
+// the user owns.
+type Webhook struct {
+	ID         uint      `json:"id" example:"1"`
+	UserID     uint      `json:"user_id" gorm:"index" example:"2"`
+	ProjectID  *uint     `json:"project_id,omitempty" gorm:"index" example:"1"`
+	URL        string    `json:"url" example:"https://ci.example.com/hooks/tasks"`
+	Secret     string    `json:"secret,omitempty" example:"s3cr3t"` // only returned when the webhook is created
+	EventTypes []string  `json:"event_types" gorm:"serializer:json" example:"task.created,task.updated"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-05-07T12:34:56Z"`
+}
+
+// WebhookRequest represents the payload for creating a webhook. A secret is
+// generated when none is given.
+type WebhookRequest struct {
+	URL        string   `json:"url" example:"https://ci.example.com/hooks/tasks"`
+	Secret     string   `json:"secret" example:"s3cr3t"`
+	ProjectID  *uint    `json:"project_id" example:"1"`
+	EventTypes []string `json:"event_types" example:"task.created,task.updated"`
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed" // gave up after the last retry
+)
+
+// WebhookDelivery is one event queued for a webhook, together with the
+// outcome of the latest attempt to send it.
+type WebhookDelivery struct {
+	ID            uint       `json:"id" example:"1"`
+	WebhookID     uint       `json:"webhook_id" gorm:"index" example:"1"`
+	EventType     string     `json:"event_type" example:"task.created"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status" gorm:"index" example:"pending"`
+	Attempts      int        `json:"attempts" example:"1"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" gorm:"index" example:"2025-05-07T12:35:26Z"`
+	StatusCode    int        `json:"status_code,omitempty" example:"502"`
+	Error         string     `json:"error,omitempty" example:"unexpected status 502"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" example:"2025-05-07T12:34:57Z"`
+	CreatedAt     time.Time  `json:"created_at" example:"2025-05-07T12:34:56Z"`
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is a key for signing or verifying access tokens. Key holds the
+// secret ([]byte) for HS256, or the private or public key for RS256 and EdDSA.
+type JWTKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    interface{}
+}
+
+var (
+	keysMu     sync.RWMutex
+	keysOnce   sync.Once
+	signingKey *JWTKey
+	verifyKeys map[string]*JWTKey
+)
+
+// SetJWTKeys replaces the signing key and the extra keys accepted when
+// verifying tokens, e.g. the previous key during a rotation. The signing
+// key is always accepted too. Every key needs its own kid; on a clash the
+// current keys are kept.
+func SetJWTKeys(signing *JWTKey, verify ...*JWTKey) error {
+	keys := map[string]*JWTKey{signing.ID: verificationKey(signing)}
+	for _, key := range verify {
+		if _, taken := keys[key.ID]; taken {
+			return fmt.Errorf("duplicate JWT key id %q", key.ID)
+		}
+		keys[key.ID] = verificationKey(key)
+	}
+
+	keysOnce.Do(func() {}) // explicit keys win over the environment
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	signingKey = signing
+	verifyKeys = keys
+	return nil
+}
+
+// LoadJWTKeysFromEnv configures the token keys from the environment:
+//
+//	JWT_ALG               HS256 (default), RS256 or EdDSA
+//	JWT_SECRET            the HS256 secret
+//	JWT_PRIVATE_KEY_FILE  PEM private key for RS256 or EdDSA
+//	JWT_KEY_ID            kid of the signing key (defaults to a key thumbprint)
+//	JWT_VERIFY_KEYS       extra kid=/path/to/public.pem keys still accepted
+//	JWT_PREVIOUS_SECRETS  extra kid=secret HS256 secrets still accepted
+//
+// Without JWT_SECRET, HS256 uses a random secret and tokens don't survive a restart.
+// A kid used twice, including the signing key's, is an error.
+func LoadJWTKeysFromEnv() error {
+	signing, err := signingKeyFromEnv()
+	if err != nil {
+		return err
+	}
+
+	var verify []*JWTKey
+	for _, entry := range splitList(os.Getenv("JWT_VERIFY_KEYS")) {
+		id, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("JWT_VERIFY_KEYS: expected kid=path, got %q", entry)
+		}
+		key, err := readPublicKey(path)
+		if err != nil {
+			return fmt.Errorf("JWT_VERIFY_KEYS %s: %w", id, err)
+		}
+		verify = append(verify, &JWTKey{ID: id, Method: methodFor(key), Key: key})
+	}
+	for _, entry := range splitList(os.Getenv("JWT_PREVIOUS_SECRETS")) {
+		id, secret, ok := strings.Cut(entry, "=")
+		if !ok || secret == "" {
+			return fmt.Errorf("JWT_PREVIOUS_SECRETS: expected kid=secret")
+		}
+		verify = append(verify, &JWTKey{ID: id, Method: jwt.SigningMethodHS256, Key: []byte(secret)})
+	}
+
+	return SetJWTKeys(signing, verify...)
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set. HS256
+// secrets are never published.
+func JWKS() map[string]interface{} {
+	currentKeys()
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	jwks := []map[string]string{}
+	for _, key := range verifyKeys {
+		switch pub := key.Key.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "RSA", "use": "sig", "alg": "RS256", "kid": key.ID,
+				"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "OKP", "use": "sig", "alg": "EdDSA", "crv": "Ed25519", "kid": key.ID,
+				"x": base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return map[string]interface{}{"keys": jwks}
+}
+
+// currentKeys returns the signing key and verification keys, loading them
+// from the environment on first use.
+func currentKeys() (*JWTKey, map[string]*JWTKey) {
+	keysOnce.Do(func() {
+		signing, err := signingKeyFromEnv()
+		if err != nil {
+			log.Fatalf("loading JWT keys: %v", err)
+		}
+		keysMu.Lock()
+		signingKey = signing
+		verifyKeys = map[string]*JWTKey{signing.ID: verificationKey(signing)}
+		keysMu.Unlock()
+	})
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	return signingKey, verifyKeys
+}
+
+func signingKeyFromEnv() (*JWTKey, error) {
+	alg := os.Getenv("JWT_ALG")
+	if alg == "" {
+		alg = "HS256"
+	}
+	id := os.Getenv("JWT_KEY_ID")
+
+	switch alg {
+	case "HS256":
+		secret := []byte(os.Getenv("JWT_SECRET"))
+		if len(secret) == 0 {
+			log.Printf("JWT_SECRET is not set, using a random secret; tokens won't survive a restart")
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+		}
+		if id == "" {
+			id = "hs256"
+		}
+		return &JWTKey{ID: id, Method: jwt.SigningMethodHS256, Key: secret}, nil
+	case "RS256", "EdDSA":
+		key, err := readPrivateKey(os.Getenv("JWT_PRIVATE_KEY_FILE"))
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		if methodFor(key).Alg() != alg {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE holds a %s key, not %s", methodFor(key).Alg(), alg)
+		}
+		signing := &JWTKey{ID: id, Method: methodFor(key), Key: key}
+		if signing.ID == "" {
+			signing.ID = Thumbprint(verificationKey(signing).Key)
+		}
+		return signing, nil
+	}
+	return nil, fmt.Errorf("unsupported JWT_ALG %q", alg)
+}
+
+// acceptedMethods lists the algorithms of the configured verification keys
+func acceptedMethods() []string {
+	_, verify := currentKeys()
+	var methods []string
+	for _, key := range verify {
+		if !containsAlg(methods, key.Method.Alg()) {
+			methods = append(methods, key.Method.Alg())
+		}
+	}
+	return methods
+}
+
+func containsAlg(algs []string, alg string) bool {
+	for _, a := range algs {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// verificationKey returns the key that checks signatures made with key
+func verificationKey(key *JWTKey) *JWTKey {
+	switch private := key.Key.(type) {
+	case *rsa.PrivateKey:
+		return &JWTKey{ID: key.ID, Method: key.Method, Key: &private.PublicKey}
+	case ed25519.PrivateKey:
+		return &JWTKey{ID: key.ID, Method: key.Method, Key: private.Public()}
+	}
+	return key
+}
+
+func methodFor(key interface{}) jwt.SigningMethod {
+	switch key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jwt.SigningMethodRS256
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodHS256
+}
+
+// Thumbprint derives a key ID from a public key
+func Thumbprint(pub interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func readPrivateKey(path string) (interface{}, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		switch key.(type) {
+		case *rsa.PrivateKey, ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func readPublicKey(path string) (interface{}, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, errors.New("only RSA and Ed25519 keys are supported")
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	if path == "" {
+		return nil, errors.New("not set")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return block, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeyRotation(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	// Tokens signed with the old key...
+	old := &JWTKey{ID: "old", Method: jwt.SigningMethodEdDSA, Key: edKey}
+	SetJWTKeys(old)
+	oldToken, err := GenerateToken(Claims{UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ...are still accepted after switching to the new one
+	SetJWTKeys(&JWTKey{ID: "new", Method: jwt.SigningMethodRS256, Key: rsaKey},
+		&JWTKey{ID: "old", Method: jwt.SigningMethodEdDSA, Key: edKey.Public()})
+	newToken, _ := GenerateToken(Claims{UserID: 2})
+	for token, userID := range map[string]uint{oldToken: 1, newToken: 2} {
+		claims, err := ParseToken(token)
+		if err != nil || claims.UserID != userID {
+			t.Fatalf("Expected user %d, got %+v, %v", userID, claims, err)
+		}
+	}
+	if parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, &Claims{}); parsed.Header["kid"] != "new" {
+		t.Fatalf("Expected the kid header, got %v", parsed.Header)
+	}
+
+	keys := JWKS()["keys"].([]map[string]string)
+	if len(keys) != 2 {
+		t.Fatalf("Expected both public keys in the JWKS, got %+v", keys)
+	}
+
+	// A token claiming a kid with a different algorithm is refused
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: 1})
+	forged.Header["kid"] = "new"
+	forgedStr, _ := forged.SignedString([]byte("guess"))
+	if _, err := ParseToken(forgedStr); err == nil {
+		t.Fatalf("Expected an algorithm mismatch to be refused")
+	}
+
+	// Dropping the old key ends its tokens
+	SetJWTKeys(&JWTKey{ID: "new", Method: jwt.SigningMethodRS256, Key: rsaKey})
+	if _, err := ParseToken(oldToken); err == nil {
+		t.Fatalf("Expected the retired key to be refused")
+	}
+	if keys := JWKS()["keys"].([]map[string]string); len(keys) != 1 || keys[0]["kty"] != "RSA" {
+		t.Fatalf("Expected only the RSA key in the JWKS, got %+v", keys)
+	}
+}
+
+func TestLoadJWTKeysFromEnv(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+
+	t.Setenv("JWT_ALG", "EdDSA")
+	t.Setenv("JWT_PRIVATE_KEY_FILE", path)
+	t.Setenv("JWT_PREVIOUS_SECRETS", "hs-2024=oldsecret")
+	if err := LoadJWTKeysFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	token, _ := GenerateToken(Claims{UserID: 7})
+	if claims, err := ParseToken(token); err != nil || claims.UserID != 7 {
+		t.Fatalf("Expected an EdDSA round trip, got %+v, %v", claims, err)
+	}
+	keys := JWKS()["keys"].([]map[string]string)
+	if len(keys) != 1 || keys[0]["kid"] != Thumbprint(edKey.Public()) {
+		t.Fatalf("Expected the Ed25519 key, without the HS256 secret, got %+v", keys)
+	}
+
+	t.Setenv("JWT_ALG", "RS256")
+	if err := LoadJWTKeysFromEnv(); err == nil {
+		t.Fatalf("Expected an error for a key of the wrong type")
+	}
+}
+
+func TestDuplicateKeyIDs(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	current := &JWTKey{ID: "current", Method: jwt.SigningMethodEdDSA, Key: edKey}
+	if err := SetJWTKeys(current); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := GenerateToken(Claims{UserID: 3})
+
+	// The default HS256 signing key is "hs256", so a previous secret can't reuse it
+	t.Setenv("JWT_ALG", "HS256")
+	t.Setenv("JWT_SECRET", "newsecret")
+	t.Setenv("JWT_PREVIOUS_SECRETS", "hs256=oldsecret")
+	if err := LoadJWTKeysFromEnv(); err == nil {
+		t.Fatalf("Expected an error for a previous secret reusing the signing kid")
+	}
+
+	t.Setenv("JWT_PREVIOUS_SECRETS", "old=one,old=two")
+	if err := LoadJWTKeysFromEnv(); err == nil {
+		t.Fatalf("Expected an error for two previous secrets with the same kid")
+	}
+
+	// A rejected configuration leaves the current keys in place
+	if claims, err := ParseToken(token); err != nil || claims.UserID != 3 {
+		t.Fatalf("Expected the current keys to be kept, got %+v, %v", claims, err)
+	}
+}
@@ -0,0 +1,139 @@
+// utils/mail.go
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// MailMessage is an email with plain text and HTML alternatives
+type MailMessage struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+// LogMailer writes emails to the log instead of sending them, for development
+// only: password reset links end up in the log too
+type LogMailer struct{}
+
+func (LogMailer) Send(msg MailMessage) error {
+	log.Printf("email to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// SMTPMailer sends mail through an SMTP server, e.g. MailHog on localhost:1025
+// during development. Username and Password are optional.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+// SMTPMailerFromEnv configures an SMTPMailer from SMTP_HOST, SMTP_PORT
+// (default 25), SMTP_FROM, SMTP_USERNAME and SMTP_PASSWORD. It returns nil
+// when SMTP_HOST isn't set.
+func SMTPMailerFromEnv() *SMTPMailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "25"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "tasks@localhost"
+	}
+	return &SMTPMailer{
+		Addr:     host + ":" + port,
+		From:     from,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+func (m *SMTPMailer) Send(msg MailMessage) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host := m.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	body, err := BuildMail(m.From, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, body)
+}
+
+// BuildMail renders msg as a multipart/alternative MIME message.
+func BuildMail(from string, msg MailMessage) ([]byte, error) {
+	if strings.ContainsAny(msg.To+msg.Subject+from, "\r\n") {
+		return nil, fmt.Errorf("mail headers must not contain line breaks")
+	}
+
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", encodeHeader(msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		if part.body == "" {
+			continue
+		}
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		qp.Close()
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.QEncoding.Encode("utf-8", s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,216 @@
+// utils/markdown.go
+package utils
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RenderMarkdown converts a small, safe subset of Markdown to HTML.
+//
+// Supported: paragraphs, # headings, - and 1. lists, > quotes, ``` code blocks,
+// **bold**, *italic*, `code`, [links](https://...) and #123 task references.
+//
+// Raw HTML in the source is always escaped, and links are only emitted for
+// http, https, mailto and relative URLs, so the output is safe to embed.
+// taskHref is called for every #123 reference and returns the link target,
+// or "" to leave the reference as plain text (e.g. the caller can't see it).
+func RenderMarkdown(src string, taskHref func(id uint) string) string {
+	r := &mdRenderer{taskHref: taskHref}
+	return r.render(src)
+}
+
+var taskRefPattern = regexp.MustCompile(`(?:^|[^\w&])#(\d+)\b`)
+
+// TaskReferences returns the IDs of all #123 style task references in src.
+func TaskReferences(src string) []uint {
+	var ids []uint
+	for _, m := range taskRefPattern.FindAllStringSubmatch(src, -1) {
+		if id, err := strconv.ParseUint(m[1], 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern      = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedItemPattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+)
+
+type mdRenderer struct {
+	taskHref func(id uint) string
+	out      strings.Builder
+}
+
+func (r *mdRenderer) render(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			r.out.WriteString("<p>" + r.inline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			r.out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := len(m[1])
+			fmt.Fprintf(&r.out, "<h%d>%s</h%d>\n", level, r.inline(m[2]), level)
+
+		case bulletPattern.MatchString(line), orderedItemPattern.MatchString(line):
+			flush()
+			pattern, tag := bulletPattern, "ul"
+			if !bulletPattern.MatchString(line) {
+				pattern, tag = orderedItemPattern, "ol"
+			}
+			r.out.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && pattern.MatchString(lines[i]); i++ {
+				item := pattern.FindStringSubmatch(lines[i])[1]
+				r.out.WriteString("<li>" + r.inline(item) + "</li>\n")
+			}
+			i--
+			r.out.WriteString("</" + tag + ">\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			r.out.WriteString("<blockquote><p>" + r.inline(strings.Join(quote, "\n")) + "</p></blockquote>\n")
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return r.out.String()
+}
+
+// inline renders emphasis, code spans, links and task references, escaping everything else.
+func (r *mdRenderer) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#>-!", rune(rest[1])):
+			b.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(rest[1:1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case rest[0] == '[':
+			if text, target, n, ok := parseLink(rest); ok {
+				if safeURL(target) {
+					b.WriteString(`<a href="` + html.EscapeString(target) + `" rel="nofollow noopener noreferrer">` + r.inline(text) + "</a>")
+				} else {
+					b.WriteString(r.inline(text))
+				}
+				i += n
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				b.WriteString("<strong>" + r.inline(rest[2:2+end]) + "</strong>")
+				i += end + 4
+				continue
+			}
+
+		case (rest[0] == '*' || rest[0] == '_') && (i == 0 || !isWordByte(s[i-1])):
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 {
+				b.WriteString("<em>" + r.inline(rest[1:1+end]) + "</em>")
+				i += end + 2
+				continue
+			}
+
+		case rest[0] == '#' && (i == 0 || (!isWordByte(s[i-1]) && s[i-1] != '&')):
+			n := 1
+			for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+				n++
+			}
+			if n > 1 && (n == len(rest) || !isWordByte(rest[n])) {
+				id, err := strconv.ParseUint(rest[1:n], 10, 64)
+				if err == nil && r.taskHref != nil {
+					if href := r.taskHref(uint(id)); href != "" {
+						b.WriteString(`<a href="` + html.EscapeString(href) + `">` + rest[:n] + "</a>")
+						i += n
+						continue
+					}
+				}
+			}
+		}
+
+		b.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+	return b.String()
+}
+
+// parseLink parses "[text](target)" at the start of s and returns its parts
+// and the number of bytes consumed.
+func parseLink(s string) (text, target string, n int, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 0 {
+		return "", "", 0, false
+	}
+	closeTarget := strings.IndexByte(s[closeText+2:], ')')
+	if closeTarget < 0 {
+		return "", "", 0, false
+	}
+	text = s[1:closeText]
+	target = strings.TrimSpace(s[closeText+2 : closeText+2+closeTarget])
+	return text, target, closeText + 3 + closeTarget, true
+}
+
+// safeURL only allows links that can't execute script when clicked.
+func safeURL(target string) bool {
+	if target == "" {
+		return false
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownSanitizes(t *testing.T) {
+	src := "# Title\n\n<script>alert(1)</script> **bold** [x](javascript:alert(1)) [ok](https://example.com)"
+	out := RenderMarkdown(src, nil)
+
+	if strings.Contains(out, "<script>") {
+		t.Fatalf("Raw HTML was not escaped: %s", out)
+	}
+	if strings.Contains(out, "javascript:") {
+		t.Fatalf("Unsafe link was rendered: %s", out)
+	}
+	for _, want := range []string{"<h1>Title</h1>", "<strong>bold</strong>", `<a href="https://example.com" rel="nofollow noopener noreferrer">ok</a>`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("Expected %q in output: %s", want, out)
+		}
+	}
+}
+
+func TestRenderMarkdownTaskReferences(t *testing.T) {
+	src := "Blocked by #12 and #13, see issue#14"
+	if refs := TaskReferences(src); len(refs) != 2 || refs[0] != 12 || refs[1] != 13 {
+		t.Fatalf("Unexpected references: %v", refs)
+	}
+
+	out := RenderMarkdown(src, func(id uint) string {
+		if id == 12 {
+			return fmt.Sprintf("/tasks/%d", id)
+		}
+		return ""
+	})
+	if !strings.Contains(out, `<a href="/tasks/12">#12</a>`) {
+		t.Fatalf("Visible task was not linked: %s", out)
+	}
+	if strings.Contains(out, `/tasks/13`) {
+		t.Fatalf("Hidden task was linked: %s", out)
+	}
+}
@@ -2,38 +2,130 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte("supersecretkey") // 🔒 Change this in production
+// Token lifetimes, configurable with ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL
+// (Go durations such as "15m" or "720h")
+var (
+	AccessTokenTTL  = durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	RefreshTokenTTL = durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+)
+
+// Issuer and audience of access tokens (JWT_ISSUER, JWT_AUDIENCE), and how
+// far clocks may drift when checking their times (JWT_LEEWAY)
+var (
+	TokenIssuer   = stringFromEnv("JWT_ISSUER", "go_task_api")
+	TokenAudience = stringFromEnv("JWT_AUDIENCE", "go_task_api")
+	TokenLeeway   = durationFromEnv("JWT_LEEWAY", 30*time.Second)
+)
 
+// Claims are the contents of an access token. SessionID names the login
+// (refresh token family) the token came from, and TokenVersion must match the
+// user's current version for the token to be accepted.
 type Claims struct {
-	UserID uint
+	UserID       uint
+	Username     string `json:"username,omitempty"`
+	Role         string `json:"role,omitempty"`
+	SessionID    string `json:"sid,omitempty"`
+	TokenVersion int    `json:"ver"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+// GenerateToken signs an access token for the user described by claims,
+// filling in the ID, issuer, audience and times.
+func GenerateToken(claims Claims) (string, error) {
+	jti, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		Issuer:    TokenIssuer,
+		Audience:  jwt.ClaimStrings{TokenAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	signing, _ := currentKeys()
+	token := jwt.NewWithClaims(signing.Method, &claims)
+	token.Header["kid"] = signing.ID
+	return token.SignedString(signing.Key)
 }
 
+// ParseToken verifies an access token's signature, algorithm, issuer,
+// audience and times, allowing TokenLeeway of clock skew.
 func ParseToken(tokenStr string) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-	if err != nil || !token.Valid {
+	token, err := jwt.ParseWithClaims(tokenStr, claims, verificationKeyFor,
+		jwt.WithValidMethods(acceptedMethods()),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(TokenAudience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(TokenLeeway),
+	)
+	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
 	return claims, nil
 }
+
+// verificationKeyFor picks the key named by the token's kid header, and
+// refuses tokens signed with any other algorithm than that key's.
+func verificationKeyFor(token *jwt.Token) (interface{}, error) {
+	_, verify := currentKeys()
+	kid, _ := token.Header["kid"].(string)
+	key, ok := verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.Key, nil
+}
+
+// NewOpaqueToken returns a random token to hand out, and the hash to store
+// in its place
+func NewOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the SHA-256 hash under which an opaque token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func stringFromEnv(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
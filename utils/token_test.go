@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signClaims(t *testing.T, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	token.Header["kid"] = "test"
+	s, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestParseTokenValidatesClaims(t *testing.T) {
+	SetJWTKeys(&JWTKey{ID: "test", Method: jwt.SigningMethodHS256, Key: []byte("secret")})
+
+	token, _ := GenerateToken(Claims{UserID: 3, Username: "ann", Role: "admin"})
+	claims, err := ParseToken(token)
+	if err != nil || claims.Username != "ann" || claims.Role != "admin" || claims.Issuer != TokenIssuer || claims.IssuedAt == nil {
+		t.Fatalf("Unexpected claims %+v, %v", claims, err)
+	}
+
+	now := time.Now()
+	valid := func() Claims {
+		return Claims{UserID: 3, RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Audience:  jwt.ClaimStrings{TokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		}}
+	}
+
+	// A few seconds of clock skew are tolerated
+	skewed := valid()
+	skewed.IssuedAt = jwt.NewNumericDate(now.Add(10 * time.Second))
+	skewed.NotBefore = skewed.IssuedAt
+	if _, err := ParseToken(signClaims(t, skewed)); err != nil {
+		t.Fatalf("Expected small clock skew to be accepted: %v", err)
+	}
+
+	wrongIssuer, wrongAudience, notYet, expired, noExpiry := valid(), valid(), valid(), valid(), valid()
+	wrongIssuer.Issuer = "someone-else"
+	wrongAudience.Audience = jwt.ClaimStrings{"other-service"}
+	notYet.NotBefore = jwt.NewNumericDate(now.Add(time.Hour))
+	expired.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour))
+	noExpiry.ExpiresAt = nil
+	for name, claims := range map[string]Claims{
+		"issuer": wrongIssuer, "audience": wrongAudience, "not before": notYet, "expired": expired, "no expiry": noExpiry,
+	} {
+		if _, err := ParseToken(signClaims(t, claims)); err == nil {
+			t.Fatalf("Expected a token with a bad %s to be refused", name)
+		}
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, valid())
+	unsigned.Header["kid"] = "test"
+	s, _ := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := ParseToken(s); err == nil {
+		t.Fatalf("Expected an unsigned token to be refused")
+	}
+}